@@ -0,0 +1,226 @@
+package log
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/brynbellomy/klog"
+)
+
+// AsyncSinkOpts specifies how an AsyncSink buffers entries.
+type AsyncSinkOpts struct {
+	QueueSize    int           // Max number of entries buffered (if 0, 1024 is used)
+	Block        bool          // If set, Write() blocks while the queue is full rather than dropping the entry
+	FlushTimeout time.Duration // Max time Flush() waits for buffered entries to be written (if 0, 5 seconds is used)
+}
+
+// AsyncSink is a Sink that queues entries and writes them to another Sink from its own goroutine,
+// so that a slow sink can't stall callers that log (e.g. OnClosing and OnClosed callbacks during shutdown).
+// Unless AsyncSinkOpts.Block is set, entries are dropped once the queue is full and periodically reported
+// (see DropReportInterval).
+//
+// Flush() (the package-level function) waits for all AsyncSinks to write their queued entries, and process roots
+// call it before their Done() channel is released.
+type AsyncSink struct {
+	opts      AsyncSinkOpts
+	sink      Sink
+	queue     chan asyncEntry
+	mu        sync.RWMutex // write-locked once closed
+	closed    bool
+	closing   chan struct{} // closed once Close() is called, releasing any blocked writes
+	closeOnce sync.Once
+	dropped   uint64 // dropped since last reported, accessed atomically
+	exited    chan struct{}
+}
+
+// asyncEntry is either an entry to write or (if flushed is non-nil) a marker that is signalled once reached.
+type asyncEntry struct {
+	buf      []byte
+	severity string // if set, passed to the sink (see SeveritySink)
+	flushed  chan struct{}
+}
+
+var (
+	gAsyncMu    sync.Mutex
+	gAsyncSinks = map[*AsyncSink]struct{}{}
+)
+
+// NewAsyncSink returns an AsyncSink that buffers entries written to it before writing them to sink.
+// Closing the returned AsyncSink flushes it and then closes sink.
+func NewAsyncSink(sink Sink, opts AsyncSinkOpts) *AsyncSink {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1024
+	}
+	if opts.FlushTimeout <= 0 {
+		opts.FlushTimeout = 5 * time.Second
+	}
+	as := &AsyncSink{
+		opts:    opts,
+		sink:    sink,
+		queue:   make(chan asyncEntry, opts.QueueSize),
+		closing: make(chan struct{}),
+		exited:  make(chan struct{}),
+	}
+	go as.writeLoop()
+
+	gAsyncMu.Lock()
+	gAsyncSinks[as] = struct{}{}
+	gAsyncMu.Unlock()
+	return as
+}
+
+func (as *AsyncSink) writeLoop() {
+	defer close(as.exited)
+	for entry := range as.queue {
+		if entry.flushed != nil {
+			close(entry.flushed)
+		} else if _, err := writeSeverity(as.sink, entry.severity, entry.buf); err != nil {
+			os.Stderr.Write(entry.buf)
+		}
+	}
+}
+
+// Write queues a copy of the given entry, returning os.ErrClosed if this AsyncSink has been closed.
+func (as *AsyncSink) Write(entry []byte) (int, error) {
+	return as.WriteSeverity("", entry)
+}
+
+// WriteSeverity is like Write() but the entry's severity is passed along to the underlying sink (see SeveritySink).
+func (as *AsyncSink) WriteSeverity(severity string, entry []byte) (int, error) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	if as.closed {
+		return 0, os.ErrClosed
+	}
+
+	queued := asyncEntry{
+		buf:      append([]byte(nil), entry...),
+		severity: severity,
+	}
+	if as.opts.Block {
+		// Give up once Close() is called so that it can't be held up by a stuck sink
+		select {
+		case as.queue <- queued:
+			return len(entry), nil
+		case <-as.closing:
+			return 0, os.ErrClosed
+		}
+	}
+	select {
+	case as.queue <- queued:
+	default:
+		atomic.AddUint64(&as.dropped, 1)
+		dropped()
+	}
+	return len(entry), nil
+}
+
+// Flush blocks until all entries queued before this call have been written (or until FlushTimeout elapses),
+// returning false if the timeout elapsed.
+func (as *AsyncSink) Flush() bool {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	if as.closed {
+		return true
+	}
+	return as.flush()
+}
+
+// flush requires as.mu to be held.
+func (as *AsyncSink) flush() bool {
+	timeout := time.NewTimer(as.opts.FlushTimeout)
+	defer timeout.Stop()
+
+	marker := asyncEntry{
+		flushed: make(chan struct{}),
+	}
+	select {
+	case as.queue <- marker:
+	case <-timeout.C:
+		return false
+	}
+	select {
+	case <-marker.flushed:
+		return true
+	case <-timeout.C:
+		return false
+	}
+}
+
+// Close flushes this AsyncSink and then closes the Sink it writes to.
+// Writes blocked on a full queue (see AsyncSinkOpts.Block) fail with os.ErrClosed.
+func (as *AsyncSink) Close() error {
+	as.closeOnce.Do(func() {
+		close(as.closing)
+	})
+	as.mu.Lock()
+	if as.closed {
+		as.mu.Unlock()
+		return nil
+	}
+	flushed := as.flush()
+	as.closed = true
+	close(as.queue)
+	as.mu.Unlock()
+
+	gAsyncMu.Lock()
+	delete(gAsyncSinks, as)
+	gAsyncMu.Unlock()
+
+	// If the sink is stuck, don't close it out from under the pending write
+	if !flushed {
+		return nil
+	}
+	<-as.exited
+	return as.sink.Close()
+}
+
+// takeDropped returns and resets the number of entries dropped since last called.
+func (as *AsyncSink) takeDropped() uint64 {
+	return atomic.SwapUint64(&as.dropped, 0)
+}
+
+// Flush writes any buffered entries, including those queued by each AsyncSink.
+// AsyncSinks are flushed in parallel, waiting no longer than the longest of their FlushTimeouts overall.
+func Flush() {
+	klog.Flush()
+
+	gAsyncMu.Lock()
+	sinks := make([]*AsyncSink, 0, len(gAsyncSinks))
+	timeout := time.Duration(0)
+	for as := range gAsyncSinks {
+		sinks = append(sinks, as)
+		if as.opts.FlushTimeout > timeout {
+			timeout = as.opts.FlushTimeout
+		}
+	}
+	gAsyncMu.Unlock()
+
+	if len(sinks) == 0 {
+		return
+	}
+	flushed := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(len(sinks))
+	for _, as := range sinks {
+		go func(as *AsyncSink) {
+			defer wg.Done()
+			as.Flush()
+		}(as)
+	}
+	go func() {
+		wg.Wait()
+		close(flushed)
+	}()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	select {
+	case <-flushed:
+	case <-deadline.C:
+	}
+}
@@ -0,0 +1,130 @@
+package log_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arcspace/go-cedar/log"
+)
+
+// slowSink is a Sink whose writes block until released.
+type slowSink struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	release chan struct{}
+	closed  bool
+}
+
+func (s *slowSink) Write(entry []byte) (int, error) {
+	<-s.release
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(entry)
+}
+
+func (s *slowSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *slowSink) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
+
+func TestAsyncSink(t *testing.T) {
+	sink := &slowSink{release: make(chan struct{})}
+	as := log.NewAsyncSink(sink, log.AsyncSinkOpts{
+		QueueSize:    2,
+		FlushTimeout: 10 * time.Millisecond,
+	})
+
+	// Writes don't block on the stalled sink, and entries beyond the queue are dropped
+	before := log.DroppedEntries()
+	start := time.Now()
+	for _, entry := range []string{"a\n", "b\n", "c\n", "d\n", "e\n"} {
+		n, err := as.Write([]byte(entry))
+		require.NoError(t, err)
+		require.Equal(t, len(entry), n)
+	}
+	require.Less(t, time.Since(start), time.Second)
+	require.Greater(t, log.DroppedEntries()-before, uint64(0))
+	require.False(t, as.Flush())
+
+	close(sink.release)
+	require.True(t, as.Flush())
+	require.Contains(t, sink.String(), "a\nb\n")
+
+	as.Write([]byte("f\n"))
+	require.NoError(t, as.Close())
+	require.True(t, sink.closed)
+	require.Contains(t, sink.String(), "f\n")
+
+	_, err := as.Write([]byte("g\n"))
+	require.Error(t, err)
+}
+
+func TestAsyncSinkCloseWhileBlocked(t *testing.T) {
+	sink := &slowSink{release: make(chan struct{})}
+	defer close(sink.release)
+	as := log.NewAsyncSink(sink, log.AsyncSinkOpts{
+		QueueSize:    1,
+		Block:        true,
+		FlushTimeout: 10 * time.Millisecond,
+	})
+
+	// The first entry stalls in the sink and the second fills the queue, so the third blocks
+	as.Write([]byte("a\n"))
+	as.Write([]byte("b\n"))
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := as.Write([]byte("c\n"))
+		blocked <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- as.Close()
+	}()
+	select {
+	case err := <-closed:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Close() blocked behind a blocked Write()")
+	}
+	require.Error(t, <-blocked)
+	require.False(t, sink.closed)
+}
+
+func TestFlushInParallel(t *testing.T) {
+	var sinks []*slowSink
+	var asyncs []*log.AsyncSink
+	for i := 0; i < 4; i++ {
+		sink := &slowSink{release: make(chan struct{})}
+		as := log.NewAsyncSink(sink, log.AsyncSinkOpts{
+			FlushTimeout: 50 * time.Millisecond,
+		})
+		as.Write([]byte("stuck\n"))
+		sinks = append(sinks, sink)
+		asyncs = append(asyncs, as)
+	}
+
+	// Each stuck sink waits out its FlushTimeout, but concurrently with the others
+	start := time.Now()
+	log.Flush()
+	require.Less(t, time.Since(start), 150*time.Millisecond)
+
+	for i, sink := range sinks {
+		close(sink.release)
+		require.NoError(t, asyncs[i].Close())
+		require.Equal(t, "stuck\n", sink.String())
+	}
+}
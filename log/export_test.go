@@ -0,0 +1,12 @@
+package log
+
+import "sync/atomic"
+
+// UseTextEncoder undoes UseJSONEncoder(), restoring klog's default header.
+func UseTextEncoder() {
+	atomic.StoreInt32(&gUseJSON, 0)
+	UseFormatter(nil)
+}
+
+// Reportf exposes reportf, which emits the log package's own warnings (e.g. drop counts).
+var Reportf = reportf
@@ -30,7 +30,7 @@ func (f Fields) Merge(f2 Fields) Fields {
 }
 
 func (f Fields) Slice() []interface{} {
-	s := make([]interface{}, len(f)*2)
+	s := make([]interface{}, 0, len(f)*2)
 	for k, v := range f {
 		s = append(s, k, v)
 	}
@@ -0,0 +1,107 @@
+package log
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/brynbellomy/klog"
+)
+
+// Level is the minimum severity a Logger emits.
+type Level int32
+
+const (
+	DefaultLevel Level = iota // Defers to the parent Logger's level (or if none, klog's -v flag)
+	Debug                     // Everything is emitted, including Info() entries of all verbose levels
+	Info                      // Debug entries are dropped and Info() entries are subject to the -v flag
+	Warn                      // Only warnings, errors, and fatal entries are emitted
+	Error                     // Only errors and fatal entries are emitted
+)
+
+func (level Level) String() string {
+	switch level {
+	case DefaultLevel:
+		return "default"
+	case Debug:
+		return "debug"
+	case Info:
+		return "info"
+	case Warn:
+		return "warn"
+	case Error:
+		return "error"
+	}
+	return "unknown"
+}
+
+// ParseLevel returns the Level having the given name (as returned by Level.String()).
+func ParseLevel(name string) (Level, error) {
+	for level := DefaultLevel; level <= Error; level++ {
+		if name == level.String() {
+			return level, nil
+		}
+	}
+	return DefaultLevel, fmt.Errorf("unknown log level %q", name)
+}
+
+// minSeverity returns the lowest severity emitted at this level.
+func (level Level) minSeverity() severity {
+	switch level {
+	case Info:
+		return sevSuccess
+	case Warn:
+		return sevWarning
+	case Error:
+		return sevError
+	}
+	return sevDebug
+}
+
+// levelNode holds a Logger's level, deferring to its parent's when unset.
+type levelNode struct {
+	level  int32
+	parent *levelNode
+}
+
+func (node *levelNode) effective() Level {
+	for ; node != nil; node = node.parent {
+		if level := Level(atomic.LoadInt32(&node.level)); level != DefaultLevel {
+			return level
+		}
+	}
+	return DefaultLevel
+}
+
+// NewChildLogger creates a new Logger with the given label that inherits the (dynamic) log level of the given parent
+// unless SetLogLevel() is called on it.  If parent is nil, this is equivalent to NewLogger().
+func NewChildLogger(parent Logger, label string) Logger {
+	l := NewLogger(label).(*logger)
+	if p, ok := parent.(*logger); ok {
+		l.level.parent = p.level
+	}
+	return l
+}
+
+// SetLogLevel sets the level of this Logger, affecting all child Loggers that don't set their own level.
+// Pass DefaultLevel to revert to inheriting the parent's level.
+func (l *logger) SetLogLevel(level Level) {
+	atomic.StoreInt32(&l.level.level, int32(level))
+}
+
+// GetLogLevel returns the level last set via SetLogLevel()
+func (l *logger) GetLogLevel() Level {
+	return Level(atomic.LoadInt32(&l.level.level))
+}
+
+// enabled returns true if entries of the given severity should be emitted.
+func (l *logger) enabled(sev severity) bool {
+	return sev >= sevFatal || sev >= l.level.effective().minSeverity()
+}
+
+// LogV returns true if logging is currently enabled for log verbose level.
+func (l *logger) LogV(inVerboseLevel int32) bool {
+	if l.level.effective() == Debug {
+		return true
+	}
+	return bool(klog.V(klog.Level(inVerboseLevel)))
+}
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+	"unsafe"
 
 	"github.com/brynbellomy/klog"
 )
@@ -30,6 +32,8 @@ type Logger interface {
 	Successf(inFormat string, args ...interface{})
 	Successw(inFormat string, fields Fields)
 	LogV(inVerboseLevel int32) bool
+	SetLogLevel(level Level)
+	GetLogLevel() Level
 	Info(inVerboseLevel int32, args ...interface{})
 	Infof(inVerboseLevel int32, inFormat string, args ...interface{})
 	Infow(inFormat string, fields Fields)
@@ -40,6 +44,9 @@ type Logger interface {
 	Errorf(inFormat string, args ...interface{})
 	Errorw(inFormat string, fields Fields)
 	Fatalf(inFormat string, args ...interface{})
+
+	// Returns a Logger that includes the given key-value pairs in every entry it logs.
+	With(keysAndValues ...interface{}) Logger
 }
 
 func InitFlags(flagset *flag.FlagSet) {
@@ -58,23 +65,27 @@ func UseFormatter(inFormatter Formatter) {
 	klog.SetFormatter(inFormatter)
 }
 
-func Flush() {
-	klog.Flush()
-}
-
 type logger struct {
-	hasPrefix bool
-	logPrefix string
-	logLabel  string
+	label    unsafe.Pointer // *string (or nil if unlabelled), accessed atomically so that SetLogLabel() can be called while logging
+	ownLabel string         // storage for the label given when created (avoiding a separate allocation)
+	lazy     *lazyLabel     // if non-nil, the label is set from lazy.fn when first needed
+	hook     Hook           // if non-nil, called with every entry (see WithHook)
+	sampler  *sampler       // if non-nil, entries are sampled per call site (see WithSampling)
+	fields   Fields         // fields set via With(), included in every entry
+	level    *levelNode     // shared with copies made via With()
+	ownLevel levelNode      // storage for level (avoiding a separate allocation)
 }
 
-var longestLabel int
+var longestLabel int32 // length of the longest log prefix, accessed atomically
 
 // NewLogger creates and inits a new Logger with the given label.
 func NewLogger(label string) Logger {
 	l := &logger{}
+	l.level = &l.ownLevel
 	if label != "" {
-		l.SetLogLabel(label)
+		l.ownLabel = label
+		l.label = unsafe.Pointer(&l.ownLabel)
+		growLongestLabel(label)
 	}
 	return l
 }
@@ -84,87 +95,139 @@ func Fatalf(inFormat string, args ...interface{}) {
 	gLogger.Fatalf(inFormat, args...)
 }
 
-var gLogger = logger{}
+var gLogger = logger{
+	level: &levelNode{},
+}
+
+// lazyLabel defers rendering a Logger's label until it is first needed.
+type lazyLabel struct {
+	once sync.Once
+	fn   func() string
+}
+
+// NewLazyChildLogger is like NewChildLogger() except that label is not called until the label is first needed
+// (typically when the Logger first emits an entry), saving the cost of rendering labels that are never logged.
+func NewLazyChildLogger(parent Logger, label func() string) Logger {
+	l := NewChildLogger(parent, "").(*logger)
+	l.lazy = &lazyLabel{fn: label}
+	return l
+}
+
+// resolveLabel sets this Logger's label if it was given lazily.
+func (l *logger) resolveLabel() {
+	if l.lazy != nil {
+		l.lazy.once.Do(func() {
+			l.setLogLabel(l.lazy.fn())
+		})
+	}
+}
 
 // SetLogLabel sets the label prefix for all entries logged.
 func (l *logger) SetLogLabel(inLabel string) {
-	l.logLabel = inLabel
-	l.hasPrefix = len(inLabel) > 0
-	if l.hasPrefix {
-		l.logPrefix = fmt.Sprintf("[%s] ", inLabel)
-		if len(l.logPrefix) > longestLabel {
-			longestLabel = len(l.logPrefix)
+	l.resolveLabel()
+	l.setLogLabel(inLabel)
+}
+
+func (l *logger) setLogLabel(inLabel string) {
+	label := new(string)
+	*label = inLabel
+	atomic.StorePointer(&l.label, unsafe.Pointer(label))
+	growLongestLabel(inLabel)
+}
+
+// getLabel returns the label last set via SetLogLabel() (not resolving a lazy label).
+func (l *logger) getLabel() string {
+	if label := (*string)(atomic.LoadPointer(&l.label)); label != nil {
+		return *label
+	}
+	return ""
+}
+
+// growLongestLabel updates longestLabel so that prefixes of shorter labels can be padded to align.
+func growLongestLabel(label string) {
+	n := int32(len(label) + len("[] "))
+	for {
+		longest := atomic.LoadInt32(&longestLabel)
+		if n <= longest || atomic.CompareAndSwapInt32(&longestLabel, longest, n) {
+			return
 		}
 	}
 }
 
+// With returns a copy of this Logger that includes the given key-value pairs in every entry it logs.
+func (l *logger) With(keysAndValues ...interface{}) Logger {
+	l.resolveLabel()
+	l2 := l.clone()
+	l2.fields = l.fields.With(keysAndValues...)
+	return l2
+}
+
+// clone returns a copy of this logger sharing its level, copying field by field so that the label is loaded atomically.
+// The lazy label (if any) must already be resolved.
+func (l *logger) clone() *logger {
+	return &logger{
+		label:   atomic.LoadPointer(&l.label),
+		hook:    l.hook,
+		sampler: l.sampler,
+		fields:  l.fields,
+		level:   l.level,
+	}
+}
+
+// Hook is passed the severity name (e.g. "warning") and message of each entry given to a Logger (see WithHook).
+type Hook func(severity string, msg string)
+
+// WithHook returns a copy of the given Logger (sharing its level) that also passes every entry to hook,
+// including entries that are dropped due to the Logger's level or the -v flag.
+// This allows recent entries to be retained (e.g. for post-mortem debugging) without emitting them.
+func WithHook(l Logger, hook Hook) Logger {
+	src, ok := l.(*logger)
+	if !ok {
+		return l
+	}
+	src.resolveLabel()
+	l2 := src.clone()
+	l2.hook = hook
+	return l2
+}
+
 // GetLogLabel returns the label last set via SetLogLabel()
 func (l *logger) GetLogLabel() string {
-	return l.logLabel
+	l.resolveLabel()
+	return l.getLabel()
 }
 
 // GetLogPrefix returns the the text that prefixes all log messages for this context.
 func (l *logger) GetLogPrefix() string {
-	return l.logPrefix
-}
-
-// LogV returns true if logging is currently enabled for log verbose level.
-func (l *logger) LogV(inVerboseLevel int32) bool {
-	return bool(klog.V(klog.Level(inVerboseLevel)))
+	l.resolveLabel()
+	if label := l.getLabel(); label != "" {
+		return "[" + label + "] "
+	}
+	return ""
 }
 
 func (l *logger) Debug(args ...interface{}) {
-	if l.hasPrefix {
-		padding := strings.Repeat(" ", longestLabel-len(l.logPrefix))
-		klog.DebugDepth(1, l.logPrefix, padding, fmt.Sprint(args...))
-	} else {
-		klog.DebugDepth(1, args...)
-	}
+	l.output(sevDebug, fmt.Sprint(args...), nil)
 }
 
 func (l *logger) Debugf(inFormat string, args ...interface{}) {
-	if l.hasPrefix {
-		padding := strings.Repeat(" ", longestLabel-len(l.logPrefix))
-		klog.DebugDepth(1, l.logPrefix, padding, fmt.Sprintf(inFormat, args...))
-	} else {
-		klog.DebugDepth(1, fmt.Sprintf(inFormat, args...))
-	}
+	l.output(sevDebug, fmt.Sprintf(inFormat, args...), nil)
 }
 
 func (l *logger) Debugw(msg string, fields Fields) {
-	if l.hasPrefix {
-		padding := strings.Repeat(" ", longestLabel-len(l.logPrefix))
-		klog.DebugDepth(1, l.logPrefix, padding, fmt.Sprintf(msg+" %v", fields))
-	} else {
-		klog.DebugDepth(1, fmt.Sprintf(msg+" %v", fields))
-	}
+	l.output(sevDebug, msg, fields)
 }
 
 func (l *logger) Success(args ...interface{}) {
-	if l.hasPrefix {
-		padding := strings.Repeat(" ", longestLabel-len(l.logPrefix))
-		klog.SuccessDepth(1, l.logPrefix, padding, fmt.Sprint(args...))
-	} else {
-		klog.SuccessDepth(1, args...)
-	}
+	l.output(sevSuccess, fmt.Sprint(args...), nil)
 }
 
 func (l *logger) Successf(inFormat string, args ...interface{}) {
-	if l.hasPrefix {
-		padding := strings.Repeat(" ", longestLabel-len(l.logPrefix))
-		klog.SuccessDepth(1, l.logPrefix, padding, fmt.Sprintf(inFormat, args...))
-	} else {
-		klog.SuccessDepth(1, fmt.Sprintf(inFormat, args...))
-	}
+	l.output(sevSuccess, fmt.Sprintf(inFormat, args...), nil)
 }
 
 func (l *logger) Successw(msg string, fields Fields) {
-	if l.hasPrefix {
-		padding := strings.Repeat(" ", longestLabel-len(l.logPrefix))
-		klog.SuccessDepth(1, l.logPrefix, padding, fmt.Sprintf(msg+" %v", fields))
-	} else {
-		klog.SuccessDepth(1, fmt.Sprintf(msg+" %v", fields))
-	}
+	l.output(sevSuccess, msg, fields)
 }
 
 // Info logs to the INFO log.
@@ -175,19 +238,13 @@ func (l *logger) Successw(msg string, fields Fields) {
 //  1. Enabled during testing and development. Use for high-level changes in state, mode, or connection.
 //  2. Enabled during low-level debugging and troubleshooting.
 func (l *logger) Info(inVerboseLevel int32, args ...interface{}) {
-	logIt := true
-	if inVerboseLevel > 0 {
-		logIt = bool(klog.V(klog.Level(inVerboseLevel)))
-	}
-
-	if logIt {
-		if l.hasPrefix {
-			padding := strings.Repeat(" ", longestLabel-len(l.logPrefix))
-			klog.InfoDepth(1, l.logPrefix, padding, fmt.Sprint(args...))
-		} else {
-			klog.InfoDepth(1, args...)
+	if inVerboseLevel > 0 && !l.LogV(inVerboseLevel) {
+		if l.hook != nil {
+			l.hook(severityName[sevInfo], fmt.Sprint(args...))
 		}
+		return
 	}
+	l.output(sevInfo, fmt.Sprint(args...), nil)
 }
 
 // Infof logs to the INFO log.
@@ -195,28 +252,17 @@ func (l *logger) Info(inVerboseLevel int32, args ...interface{}) {
 //
 // See comments above for Info() for guidelines for inVerboseLevel.
 func (l *logger) Infof(inVerboseLevel int32, inFormat string, args ...interface{}) {
-	logIt := true
-	if inVerboseLevel > 0 {
-		logIt = bool(klog.V(klog.Level(inVerboseLevel)))
-	}
-
-	if logIt {
-		if l.hasPrefix {
-			padding := strings.Repeat(" ", longestLabel-len(l.logPrefix))
-			klog.InfoDepth(1, l.logPrefix, padding, fmt.Sprintf(inFormat, args...))
-		} else {
-			klog.InfoDepth(1, fmt.Sprintf(inFormat, args...))
+	if inVerboseLevel > 0 && !l.LogV(inVerboseLevel) {
+		if l.hook != nil {
+			l.hook(severityName[sevInfo], fmt.Sprintf(inFormat, args...))
 		}
+		return
 	}
+	l.output(sevInfo, fmt.Sprintf(inFormat, args...), nil)
 }
 
 func (l *logger) Infow(msg string, fields Fields) {
-	if l.hasPrefix {
-		padding := strings.Repeat(" ", longestLabel-len(l.logPrefix))
-		klog.InfoDepth(1, l.logPrefix, padding, fmt.Sprintf(msg+" %v", fields))
-	} else {
-		klog.InfoDepth(1, fmt.Sprintf(msg+" %v", fields))
-	}
+	l.output(sevInfo, msg, fields)
 }
 
 // Warn logs to the WARNING and INFO logs.
@@ -225,12 +271,7 @@ func (l *logger) Infow(msg string, fields Fields) {
 // Warnings are reserved for situations that indicate an inconsistency or an error that
 // won't result in a departure of specifications, correctness, or expected behavior.
 func (l *logger) Warn(args ...interface{}) {
-	if l.hasPrefix {
-		padding := strings.Repeat(" ", longestLabel-len(l.logPrefix))
-		klog.WarningDepth(1, l.logPrefix, padding, fmt.Sprint(args...))
-	} else {
-		klog.WarningDepth(1, args...)
-	}
+	l.output(sevWarning, fmt.Sprint(args...), nil)
 }
 
 // Warnf logs to the WARNING and INFO logs.
@@ -238,21 +279,11 @@ func (l *logger) Warn(args ...interface{}) {
 //
 // See comments above for Warn() for guidelines on errors vs warnings.
 func (l *logger) Warnf(inFormat string, args ...interface{}) {
-	if l.hasPrefix {
-		padding := strings.Repeat(" ", longestLabel-len(l.logPrefix))
-		klog.WarningDepth(1, l.logPrefix, padding, fmt.Sprintf(inFormat, args...))
-	} else {
-		klog.WarningDepth(1, fmt.Sprintf(inFormat, args...))
-	}
+	l.output(sevWarning, fmt.Sprintf(inFormat, args...), nil)
 }
 
 func (l *logger) Warnw(msg string, fields Fields) {
-	if l.hasPrefix {
-		padding := strings.Repeat(" ", longestLabel-len(l.logPrefix))
-		klog.WarningDepth(1, l.logPrefix, padding, fmt.Sprintf(msg+" %v", fields))
-	} else {
-		klog.WarningDepth(1, fmt.Sprintf(msg+" %v", fields))
-	}
+	l.output(sevWarning, msg, fields)
 }
 
 // Error logs to the ERROR, WARNING, and INFO logs.
@@ -262,14 +293,7 @@ func (l *logger) Warnw(msg string, fields Fields) {
 // corruption of data or resources, or an issue that if not addressed could spiral into deeper issues.
 // Logging an error reflects that correctness or expected behavior is either broken or under threat.
 func (l *logger) Error(args ...interface{}) {
-	{
-		if l.hasPrefix {
-			padding := strings.Repeat(" ", longestLabel-len(l.logPrefix))
-			klog.ErrorDepth(1, l.logPrefix, padding, fmt.Sprint(args...))
-		} else {
-			klog.ErrorDepth(1, args...)
-		}
-	}
+	l.output(sevError, fmt.Sprint(args...), nil)
 }
 
 // Errorf logs to the ERROR, WARNING, and INFO logs.
@@ -277,36 +301,17 @@ func (l *logger) Error(args ...interface{}) {
 //
 // See comments above for Error() for guidelines on errors vs warnings.
 func (l *logger) Errorf(inFormat string, args ...interface{}) {
-	{
-		if l.hasPrefix {
-			padding := strings.Repeat(" ", longestLabel-len(l.logPrefix))
-			klog.ErrorDepth(1, l.logPrefix, padding, fmt.Sprintf(inFormat, args...))
-		} else {
-			klog.ErrorDepth(1, fmt.Sprintf(inFormat, args...))
-		}
-	}
+	l.output(sevError, fmt.Sprintf(inFormat, args...), nil)
 }
 
 func (l *logger) Errorw(msg string, fields Fields) {
-	if l.hasPrefix {
-		padding := strings.Repeat(" ", longestLabel-len(l.logPrefix))
-		klog.ErrorDepth(1, l.logPrefix, padding, fmt.Sprintf(msg+" %v", fields))
-	} else {
-		klog.ErrorDepth(1, fmt.Sprintf(msg+" %v", fields))
-	}
+	l.output(sevError, msg, fields)
 }
 
 // Fatalf logs to the FATAL, ERROR, WARNING, and INFO logs,
 // Arguments are handled like fmt.Printf(); a newline is appended if missing.
 func (l *logger) Fatalf(inFormat string, args ...interface{}) {
-	{
-		if l.hasPrefix {
-			padding := strings.Repeat(" ", longestLabel-len(l.logPrefix))
-			klog.FatalDepth(1, l.logPrefix, padding, fmt.Sprintf(inFormat, args...))
-		} else {
-			klog.FatalDepth(1, fmt.Sprintf(inFormat, args...))
-		}
-	}
+	l.output(sevFatal, fmt.Sprintf(inFormat, args...), nil)
 }
 
 func AwaitInterrupt() (
@@ -0,0 +1,206 @@
+package log_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arcspace/go-cedar/log"
+)
+
+// captureSink is a SeveritySink that retains each entry written to it.
+type captureSink struct {
+	mu         sync.Mutex
+	entries    []string
+	severities []string
+}
+
+func (s *captureSink) Write(entry []byte) (int, error) {
+	return s.WriteSeverity("", entry)
+}
+
+func (s *captureSink) WriteSeverity(severity string, entry []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, string(entry))
+	s.severities = append(s.severities, severity)
+	return len(entry), nil
+}
+
+func (s *captureSink) Close() error { return nil }
+
+// last returns the most recent entry and its severity.
+func (s *captureSink) last(t *testing.T) (entry, severity string) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	require.NotEmpty(t, s.entries)
+	n := len(s.entries) - 1
+	return strings.TrimSpace(s.entries[n]), s.severities[n]
+}
+
+// capture directs log output to a new captureSink until the test completes.
+func capture(t *testing.T) *captureSink {
+	sink := &captureSink{}
+	log.SetOutput(sink)
+	t.Cleanup(func() {
+		log.SetOutput(log.WriterSink(os.Stderr))
+	})
+	return sink
+}
+
+func TestWith(t *testing.T) {
+	sink := capture(t)
+
+	l := log.NewLogger("with")
+	l2 := l.With("a", 1, "b", 2)
+	l3 := l2.With("b", 3)
+
+	l3.Warnw("merged", log.Fields{"c": 4})
+	entry, _ := sink.last(t)
+	require.Contains(t, entry, "[with]")
+	require.True(t, strings.HasSuffix(entry, " merged a=1 b=3 c=4"), entry)
+
+	// Fields given to an entry override those given to With(), and copies don't affect each other
+	l2.Warnw("override", log.Fields{"a": "x"})
+	entry, _ = sink.last(t)
+	require.True(t, strings.HasSuffix(entry, " override a=x b=2"), entry)
+
+	l.Warn("plain")
+	entry, _ = sink.last(t)
+	require.True(t, strings.HasSuffix(entry, " plain"), entry)
+
+	// Copies share the original's level
+	l.SetLogLevel(log.Error)
+	l3.Warn("dropped")
+	entry, _ = sink.last(t)
+	require.NotContains(t, entry, "dropped")
+	l.SetLogLevel(log.DefaultLevel)
+}
+
+func TestWithWhileRelabeling(t *testing.T) {
+	capture(t)
+	l := log.NewLogger("relabeled")
+
+	// Copies can be made while the label is being changed (run with -race)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			l.SetLogLabel(fmt.Sprintf("relabeled-%d", i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			log.WithHook(log.WithSampling(l.With("i", i), log.SamplingOpts{}), func(string, string) {}).Warn("copied")
+		}
+	}()
+	wg.Wait()
+
+	require.Equal(t, "relabeled-99", l.GetLogLabel())
+	require.Equal(t, "relabeled-99", l.With("k", "v").GetLogLabel())
+}
+
+func TestCallerDepth(t *testing.T) {
+	sink := capture(t)
+	l := log.NewLogger("caller")
+
+	// Each Logger method reports its caller's line, whether called directly or on a copy made via With()
+	for _, emit := range []func(){
+		func() { l.Warn("warn") },
+		func() { l.Warnf("warnf %d", 1) },
+		func() { l.Errorw("errorw", nil) },
+		func() { l.Info(0, "info") },
+		func() { l.With("k", "v").Successf("success") },
+	} {
+		emit()
+		entry, _ := sink.last(t)
+		require.Contains(t, entry, "logger_test.go:", entry)
+	}
+}
+
+func TestJSONEncoder(t *testing.T) {
+	sink := capture(t)
+	log.UseJSONEncoder()
+	defer log.UseTextEncoder()
+
+	l := log.NewLogger("json").With("user", "o'neil \"the\" <admin>", "err", io.EOF)
+	l.Warnw("line one\nline two", log.Fields{"n": 3})
+
+	entry, severity := sink.last(t)
+	require.Equal(t, "warning", severity)
+	require.NotContains(t, entry, "\n")
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(entry), &decoded), entry)
+	require.Equal(t, "warning", decoded["level"])
+	require.Equal(t, "json", decoded["label"])
+	require.Equal(t, "line one\nline two", decoded["msg"])
+	require.Equal(t, "o'neil \"the\" <admin>", decoded["user"])
+	require.Equal(t, "EOF", decoded["err"])
+	require.Equal(t, float64(3), decoded["n"])
+	require.Contains(t, decoded["caller"], "logger_test.go:")
+	require.NotEmpty(t, decoded["time"])
+
+	// Values that can't be encoded still produce a valid entry
+	l.Warnw("unencodable", log.Fields{"ch": make(chan int)})
+	entry, _ = sink.last(t)
+	require.NoError(t, json.Unmarshal([]byte(entry), &decoded), entry)
+	require.Equal(t, "unencodable", decoded["msg"])
+	require.NotEmpty(t, decoded["error"])
+}
+
+func TestReportJSON(t *testing.T) {
+	sink := capture(t)
+	log.UseJSONEncoder()
+	defer log.UseTextEncoder()
+
+	// Drop counts are encoded like any other entry
+	log.Reportf("sampled", "dropped %d log entries due to sampling", 3)
+	entry, severity := sink.last(t)
+	require.Equal(t, "warning", severity)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(entry), &decoded), entry)
+	require.Equal(t, "warning", decoded["level"])
+	require.Equal(t, "sampled", decoded["label"])
+	require.Equal(t, "dropped 3 log entries due to sampling", decoded["msg"])
+	require.Contains(t, decoded["caller"], "logger_test.go:")
+
+	log.Reportf("", "dropped %d log entries due to rate limit", 2)
+	entry, _ = sink.last(t)
+	decoded = nil
+	require.NoError(t, json.Unmarshal([]byte(entry), &decoded), entry)
+	require.NotContains(t, decoded, "label")
+}
+
+func TestSinkSeverity(t *testing.T) {
+	sink := capture(t)
+	l := log.NewLogger("severity")
+
+	l.Info(0, "info")
+	_, severity := sink.last(t)
+	require.Equal(t, "info", severity)
+
+	l.Warn("warn")
+	_, severity = sink.last(t)
+	require.Equal(t, "warning", severity)
+
+	l.Error("error")
+	_, severity = sink.last(t)
+	require.Equal(t, "error", severity)
+
+	// Each entry is written once regardless of severity
+	sink.mu.Lock()
+	n := len(sink.entries)
+	sink.mu.Unlock()
+	require.Equal(t, 3, n)
+}
@@ -0,0 +1,167 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/brynbellomy/klog"
+)
+
+type severity int32
+
+const (
+	sevDebug severity = iota
+	sevSuccess
+	sevInfo
+	sevWarning
+	sevError
+	sevFatal
+)
+
+var severityName = [...]string{
+	sevDebug:   "debug",
+	sevSuccess: "success",
+	sevInfo:    "info",
+	sevWarning: "warning",
+	sevError:   "error",
+	sevFatal:   "fatal",
+}
+
+// outputDepth is the number of stack frames between output() and the caller of a Logger method (see emit).
+const outputDepth = 3
+
+var gUseJSON int32
+
+// UseJSONEncoder causes all log entries to be emitted as single-line JSON objects (with the fields
+// "time", "level", "caller", "label", "msg", followed by any structured fields) to whatever output klog uses.
+func UseJSONEncoder() {
+	UseFormatter(noHeader{})
+	atomic.StoreInt32(&gUseJSON, 1)
+}
+
+// noHeader is a Formatter that emits no header since JSON entries are self-describing.
+type noHeader struct{}
+
+func (noHeader) FormatHeader(severity string, filename string, lineNum int, ioBuf *bytes.Buffer) {}
+
+// output emits a log entry of the given severity, where fields (if any) are merged with this logger's fields.
+func (l *logger) output(sev severity, msg string, fields Fields) {
+	if l.hook != nil {
+		hookMsg := msg
+		if len(fields) > 0 {
+			hookMsg = msg + " " + fields.String()
+		}
+		l.hook(severityName[sev], hookMsg)
+	}
+	if !l.enabled(sev) || !l.admit(sev) {
+		return
+	}
+	l.resolveLabel()
+	if len(l.fields) > 0 {
+		fields = l.fields.Merge(fields)
+	}
+	l.emit(outputDepth, sev, msg, fields)
+}
+
+// emit writes a log entry as text or JSON (see UseJSONEncoder), where depth is the number of stack frames above emit
+// of the call site the entry is attributed to (1 being emit's caller).
+func (l *logger) emit(depth int, sev severity, msg string, fields Fields) {
+	if atomic.LoadInt32(&gUseJSON) != 0 {
+		logDepth(depth+1, sev, l.encodeJSON(depth+1, sev, msg, fields))
+		return
+	}
+
+	if len(fields) > 0 {
+		msg = msg + " " + fields.String()
+	}
+	if label := l.getLabel(); label != "" {
+		padding := strings.Repeat(" ", int(atomic.LoadInt32(&longestLabel))-len(label)-len("[] "))
+		logDepth(depth+1, sev, "[", label, "] ", padding, msg)
+	} else {
+		logDepth(depth+1, sev, msg)
+	}
+}
+
+// reportf emits a warning from the log package itself (under the given label), bypassing levels, sampling, and rate limits.
+func reportf(label string, format string, args ...interface{}) {
+	l := &logger{}
+	if label != "" {
+		l.ownLabel = label
+		l.label = unsafe.Pointer(&l.ownLabel)
+	}
+	l.emit(2, sevWarning, fmt.Sprintf(format, args...), nil)
+}
+
+// encodeJSON renders a log entry as a JSON object, where depth is the number of stack frames above encodeJSON of its call site.
+func (l *logger) encodeJSON(depth int, sev severity, msg string, fields Fields) string {
+	entry := make(map[string]interface{}, len(fields)+5)
+	for k, v := range fields {
+		if err, isErr := v.(error); isErr {
+			v = err.Error()
+		}
+		entry[k] = v
+	}
+	entry["time"] = time.Now().Format(time.RFC3339Nano)
+	entry["level"] = severityName[sev]
+	entry["msg"] = msg
+	if label := l.getLabel(); label != "" {
+		entry["label"] = label
+	}
+	if _, file, line, ok := runtime.Caller(depth); ok {
+		entry["caller"] = fmt.Sprintf("%s:%d", path.Base(file), line)
+	}
+
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		buf, _ = json.Marshal(map[string]interface{}{
+			"level": severityName[sev],
+			"msg":   msg,
+			"error": err.Error(),
+		})
+	}
+	return string(buf)
+}
+
+// String renders Fields as space-separated key=value pairs in key order.
+func (f Fields) String() string {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf := strings.Builder{}
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%s=%v", k, f[k])
+	}
+	return buf.String()
+}
+
+// logDepth passes args to klog, where depth is the number of stack frames above logDepth of the entry's call site.
+func logDepth(depth int, sev severity, args ...interface{}) {
+	switch sev {
+	case sevDebug:
+		klog.DebugDepth(depth, args...)
+	case sevSuccess:
+		klog.SuccessDepth(depth, args...)
+	case sevInfo:
+		klog.InfoDepth(depth, args...)
+	case sevWarning:
+		klog.WarningDepth(depth, args...)
+	case sevError:
+		klog.ErrorDepth(depth, args...)
+	case sevFatal:
+		klog.FatalDepth(depth, args...)
+	}
+}
@@ -0,0 +1,217 @@
+package log
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SamplingOpts specifies how a Logger samples entries logged from each call site (see WithSampling).
+//
+// For example, SamplingOpts{First: 5, Thereafter: 100} emits the first 5 entries logged from a given line
+// during each Tick and then every 100th entry after that.
+type SamplingOpts struct {
+	First      int           // Number of entries emitted per call site per Tick before sampling begins
+	Thereafter int           // Once sampling, every Nth entry is emitted (if 0, all remaining entries during the Tick are dropped)
+	Tick       time.Duration // Interval after which each call site's count resets (if 0, one second is used)
+}
+
+// WithSampling returns a copy of the given Logger (sharing its level) that samples entries per call site
+// as specified by opts, so that a hot loop can't flood the log.  Fatal entries are never dropped.
+// Copies made via With() share the same sampling state.
+//
+// The number of dropped entries is periodically logged as a warning (see DropReportInterval).
+func WithSampling(l Logger, opts SamplingOpts) Logger {
+	src, ok := l.(*logger)
+	if !ok {
+		return l
+	}
+	if opts.Tick <= 0 {
+		opts.Tick = time.Second
+	}
+	src.resolveLabel()
+	l2 := src.clone()
+	l2.sampler = &sampler{
+		opts:  opts,
+		label: src.getLabel(),
+		sites: make(map[uintptr]*siteCount),
+	}
+	return l2
+}
+
+// sampler tracks the number of entries logged from each call site during the current Tick.
+type sampler struct {
+	opts    SamplingOpts
+	label   string // label of the Logger given to WithSampling()
+	mu      sync.Mutex
+	sites   map[uintptr]*siteCount
+	dropped uint64 // dropped since last reported
+}
+
+type siteCount struct {
+	since time.Time
+	count int
+}
+
+func (s *sampler) admit(site uintptr, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc := s.sites[site]
+	if sc == nil {
+		sc = &siteCount{}
+		s.sites[site] = sc
+	}
+	if now.Sub(sc.since) >= s.opts.Tick {
+		sc.since = now
+		sc.count = 0
+	}
+	sc.count++
+
+	n := sc.count - s.opts.First
+	if n <= 0 {
+		return true
+	}
+	return s.opts.Thereafter > 0 && n%s.opts.Thereafter == 0
+}
+
+// rateLimit is a token bucket capping the number of entries emitted per second across all Loggers.
+type rateLimit struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+	dropped uint64 // dropped since last reported
+}
+
+var (
+	gRateLimited int32 // non-zero if gRateLimit is active
+	gRateLimit   rateLimit
+)
+
+// SetRateLimit caps the number of entries emitted per second across all Loggers, allowing bursts of up to burst entries.
+// Entries beyond the cap are dropped (except fatal entries) and periodically reported (see DropReportInterval).
+// Passing a rate <= 0 removes the cap.
+func SetRateLimit(entriesPerSec float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+	gRateLimit.mu.Lock()
+	gRateLimit.rate = entriesPerSec
+	gRateLimit.burst = float64(burst)
+	gRateLimit.tokens = float64(burst)
+	gRateLimit.last = time.Now()
+	gRateLimit.mu.Unlock()
+
+	if entriesPerSec > 0 {
+		atomic.StoreInt32(&gRateLimited, 1)
+	} else {
+		atomic.StoreInt32(&gRateLimited, 0)
+	}
+}
+
+func (rl *rateLimit) admit(now time.Time) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.tokens += now.Sub(rl.last).Seconds() * rl.rate
+	if rl.tokens > rl.burst {
+		rl.tokens = rl.burst
+	}
+	rl.last = now
+	if rl.tokens < 1 {
+		rl.dropped++
+		return false
+	}
+	rl.tokens--
+	return true
+}
+
+// DropReportInterval is how often the number of entries dropped by sampling, SetRateLimit(), or an AsyncSink is logged.
+var DropReportInterval = 10 * time.Second
+
+var (
+	gDropCount    uint64 // total entries ever dropped
+	gDropMu       sync.Mutex
+	gDropSamplers = map[*sampler]struct{}{} // samplers with drops not yet reported
+	gDropReporter sync.Once
+)
+
+// DroppedEntries returns the total number of entries dropped by sampling, SetRateLimit(), or an AsyncSink since the process started.
+func DroppedEntries() uint64 {
+	return atomic.LoadUint64(&gDropCount)
+}
+
+// admit returns true if an entry of the given severity logged by the caller of a Logger method should be emitted.
+func (l *logger) admit(sev severity) bool {
+	if sev >= sevFatal || (l.sampler == nil && atomic.LoadInt32(&gRateLimited) == 0) {
+		return true
+	}
+	now := time.Now()
+
+	if l.sampler != nil {
+		var pcs [1]uintptr
+		runtime.Callers(outputDepth+1, pcs[:]) // skips runtime.Callers, admit, output, and the Logger method
+		if !l.sampler.admit(pcs[0], now) {
+			gDropMu.Lock()
+			l.sampler.dropped++
+			gDropSamplers[l.sampler] = struct{}{}
+			gDropMu.Unlock()
+			dropped()
+			return false
+		}
+	}
+
+	if atomic.LoadInt32(&gRateLimited) != 0 && !gRateLimit.admit(now) {
+		dropped()
+		return false
+	}
+	return true
+}
+
+func dropped() {
+	atomic.AddUint64(&gDropCount, 1)
+	gDropReporter.Do(func() {
+		go reportDrops()
+	})
+}
+
+// reportDrops periodically logs the number of entries dropped since last reported.
+func reportDrops() {
+	for {
+		time.Sleep(DropReportInterval)
+
+		gDropMu.Lock()
+		samplers := gDropSamplers
+		gDropSamplers = map[*sampler]struct{}{}
+		for s := range samplers {
+			if s.dropped > 0 {
+				reportf(s.label, "dropped %d log entries due to sampling", s.dropped)
+				s.dropped = 0
+			}
+		}
+		gDropMu.Unlock()
+
+		gRateLimit.mu.Lock()
+		dropped := gRateLimit.dropped
+		gRateLimit.dropped = 0
+		gRateLimit.mu.Unlock()
+		if dropped > 0 {
+			reportf("", "dropped %d log entries due to rate limit", dropped)
+		}
+
+		gAsyncMu.Lock()
+		sinks := make([]*AsyncSink, 0, len(gAsyncSinks))
+		for as := range gAsyncSinks {
+			sinks = append(sinks, as)
+		}
+		gAsyncMu.Unlock()
+		for _, as := range sinks {
+			if dropped := as.takeDropped(); dropped > 0 {
+				reportf("", "dropped %d log entries due to a full AsyncSink queue", dropped)
+			}
+		}
+	}
+}
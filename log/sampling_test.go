@@ -0,0 +1,40 @@
+package log_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arcspace/go-cedar/log"
+)
+
+func TestSampling(t *testing.T) {
+	l := log.WithSampling(log.NewLogger("sampled"), log.SamplingOpts{
+		First:      2,
+		Thereafter: 3,
+	})
+
+	before := log.DroppedEntries()
+	for i := 0; i < 10; i++ {
+		l.Warnf("hot loop %d", i) // emits 0, 1, 4, 7
+	}
+	require.Equal(t, uint64(6), log.DroppedEntries()-before)
+
+	// Each call site is sampled separately
+	before = log.DroppedEntries()
+	l.Warn("elsewhere")
+	l.With("k", 1).Warn("elsewhere")
+	require.Equal(t, uint64(0), log.DroppedEntries()-before)
+}
+
+func TestRateLimit(t *testing.T) {
+	log.SetRateLimit(1, 3)
+	defer log.SetRateLimit(0, 0)
+
+	l := log.NewLogger("limited")
+	before := log.DroppedEntries()
+	for i := 0; i < 5; i++ {
+		l.Warnf("burst %d", i)
+	}
+	require.Equal(t, uint64(2), log.DroppedEntries()-before)
+}
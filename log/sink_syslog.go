@@ -0,0 +1,59 @@
+//go:build !windows && !plan9
+
+package log
+
+import (
+	"log/syslog"
+)
+
+// NewSyslogSink returns a Sink that forwards entries to the local syslog daemon under the given tag.
+// Each entry's syslog priority is derived from its severity as passed by SetOutput() (see SeveritySink),
+// or when written directly via Write(), from the severity letter leading its header (e.g. 'W' for WARNING).
+func NewSyslogSink(tag string) (Sink, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{w}, nil
+}
+
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+func (s *syslogSink) Write(entry []byte) (int, error) {
+	severity := severityName[sevInfo]
+	if len(entry) > 0 {
+		for sev, char := range "DSIWEF" { // klog's header letters, in severity order
+			if entry[0] == byte(char) {
+				severity = severityName[sev]
+			}
+		}
+	}
+	return s.WriteSeverity(severity, entry)
+}
+
+func (s *syslogSink) WriteSeverity(severity string, entry []byte) (int, error) {
+	var err error
+	msg := string(entry)
+	switch severity {
+	case severityName[sevDebug]:
+		err = s.w.Debug(msg)
+	case severityName[sevWarning]:
+		err = s.w.Warning(msg)
+	case severityName[sevError]:
+		err = s.w.Err(msg)
+	case severityName[sevFatal]:
+		err = s.w.Crit(msg)
+	default:
+		err = s.w.Info(msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(entry), nil
+}
+
+func (s *syslogSink) Close() error {
+	return s.w.Close()
+}
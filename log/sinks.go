@@ -0,0 +1,301 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brynbellomy/klog"
+)
+
+// Sink is a destination for formatted log entries.
+type Sink interface {
+	io.Writer
+	io.Closer
+}
+
+// SeveritySink is a Sink that handles each entry according to its severity (e.g. to map it to a syslog priority).
+// SetOutput(), MultiSink, and AsyncSink call WriteSeverity() rather than Write() on such sinks, passing the entry's
+// severity name as passed to a Hook (e.g. "warning"), so that the severity doesn't depend on how entries are encoded.
+type SeveritySink interface {
+	Sink
+	WriteSeverity(severity string, entry []byte) (int, error)
+}
+
+// writeSeverity writes the given entry to sink, passing its severity if sink is a SeveritySink.
+func writeSeverity(sink Sink, severity string, entry []byte) (int, error) {
+	if ss, ok := sink.(SeveritySink); ok && severity != "" {
+		return ss.WriteSeverity(severity, entry)
+	}
+	return sink.Write(entry)
+}
+
+// SetOutput directs all log entries (of INFO severity and higher) to the given sinks, replacing any sinks previously set.
+// Note that entries are still also written to stderr if -logtostderr (or -alsologtostderr) is set.
+// Previously set sinks are not closed, so the caller remains responsible for closing them.
+func SetOutput(sinks ...Sink) {
+	out := WriterSink(io.Discard)
+	switch len(sinks) {
+	case 0:
+	case 1:
+		out = sinks[0]
+	default:
+		out = MultiSink(sinks...)
+	}
+
+	// klog writes each entry to the writer of its severity and then to those of each lower severity down to INFO, all while
+	// holding its lock.  So the WARNING, ERROR, and FATAL writers only note the entry's severity, and the INFO writer
+	// passes each entry (exactly once) to out along with the severity noted.
+	demux := &severityDemux{out: out}
+	klog.SetOutputBySeverity("INFO", demux)
+	klog.SetOutputBySeverity("WARNING", severityMark{demux, severityName[sevWarning]})
+	klog.SetOutputBySeverity("ERROR", severityMark{demux, severityName[sevError]})
+	klog.SetOutputBySeverity("FATAL", severityMark{demux, severityName[sevFatal]})
+}
+
+// severityDemux passes each entry klog writes to out along with the entry's severity (see SetOutput).
+type severityDemux struct {
+	out      Sink
+	severity string // severity of the entry being written if above INFO, accessed under klog's lock
+}
+
+func (d *severityDemux) Write(entry []byte) (int, error) {
+	severity := d.severity
+	if severity == "" {
+		severity = severityName[sevInfo]
+	}
+	d.severity = ""
+	return writeSeverity(d.out, severity, entry)
+}
+
+// severityMark notes the severity of each entry written to it, which klog writes before writing it to the INFO writer.
+type severityMark struct {
+	demux    *severityDemux
+	severity string
+}
+
+func (m severityMark) Write(entry []byte) (int, error) {
+	if m.demux.severity == "" {
+		m.demux.severity = m.severity // klog writes to the entry's own severity first
+	}
+	return len(entry), nil
+}
+
+// WriterSink adapts an io.Writer (e.g. os.Stdout) into a Sink whose Close() is a no-op.
+func WriterSink(w io.Writer) Sink {
+	return writerSink{w}
+}
+
+type writerSink struct {
+	io.Writer
+}
+
+func (writerSink) Close() error { return nil }
+
+// MultiSink returns a Sink that writes each entry to all the given sinks, continuing past any that fail.
+func MultiSink(sinks ...Sink) Sink {
+	return multiSink(append([]Sink(nil), sinks...))
+}
+
+type multiSink []Sink
+
+func (ms multiSink) Write(entry []byte) (int, error) {
+	var firstErr error
+	for _, si := range ms {
+		if _, err := si.Write(entry); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return len(entry), firstErr
+}
+
+func (ms multiSink) WriteSeverity(severity string, entry []byte) (int, error) {
+	var firstErr error
+	for _, si := range ms {
+		if _, err := writeSeverity(si, severity, entry); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return len(entry), firstErr
+}
+
+func (ms multiSink) Close() error {
+	var firstErr error
+	for _, si := range ms {
+		if err := si.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// RotatingFileOpts specifies when a RotatingFile rotates and how many rotated files are retained.
+type RotatingFileOpts struct {
+	MaxSize    int64         // If > 0, the file is rotated before it would exceed this many bytes.
+	MaxAge     time.Duration // If > 0, the file is rotated once it has been open this long.
+	MaxBackups int           // If > 0, only this many of the most recent rotated files are kept.
+}
+
+// RotatingFile is a Sink that appends to a file, rotating it when it grows too large or too old.
+// Rotated files are renamed with a timestamp suffix, e.g. "server.log.20060102-150405.000".
+type RotatingFile struct {
+	opts     RotatingFileOpts
+	pathname string
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	opened   time.Time
+}
+
+// NewRotatingFile opens (or creates) the given log file for appending.
+func NewRotatingFile(pathname string, opts RotatingFileOpts) (*RotatingFile, error) {
+	rf := &RotatingFile{
+		opts:     opts,
+		pathname: pathname,
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *RotatingFile) open() error {
+	file, err := os.OpenFile(rf.pathname, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rf.file = file
+	rf.size = info.Size()
+	rf.opened = time.Now()
+	return nil
+}
+
+func (rf *RotatingFile) Write(entry []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	needsRotate := rf.opts.MaxSize > 0 && rf.size > 0 && rf.size+int64(len(entry)) > rf.opts.MaxSize
+	needsRotate = needsRotate || (rf.opts.MaxAge > 0 && time.Since(rf.opened) >= rf.opts.MaxAge)
+	if needsRotate {
+		if err := rf.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "log: failed to rotate %q: %v\n", rf.pathname, err)
+		}
+		if rf.file == nil {
+			return 0, os.ErrClosed
+		}
+	}
+
+	n, err := rf.file.Write(entry)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, renames it with a timestamp suffix, and opens a new file in its place.
+// If the file can't be renamed or reopened, the error is returned and writes continue to the current file.
+func (rf *RotatingFile) Rotate() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.rotate()
+}
+
+// rotatedSuffix is the time layout of the suffix appended to a rotated file's name.
+const rotatedSuffix = "20060102-150405.000"
+
+func (rf *RotatingFile) rotate() error {
+	if rf.file == nil {
+		return os.ErrClosed
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	if err != nil {
+		return rf.reopen(err)
+	}
+
+	rotated := rf.pathname + "." + time.Now().Format(rotatedSuffix)
+	if err = os.Rename(rf.pathname, rotated); err != nil {
+		return rf.reopen(err)
+	}
+	if err = rf.open(); err != nil {
+		// Move the rotated file back so writes continue where they left off
+		if os.Rename(rotated, rf.pathname) == nil {
+			return rf.reopen(err)
+		}
+		return err
+	}
+	return rf.pruneBackups()
+}
+
+// reopen reopens the file at pathname after a failed rotation so that logging isn't lost, returning the given error.
+func (rf *RotatingFile) reopen(err error) error {
+	if openErr := rf.open(); openErr != nil {
+		fmt.Fprintf(os.Stderr, "log: failed to reopen %q: %v\n", rf.pathname, openErr)
+	}
+	return err
+}
+
+// pruneBackups removes the oldest rotated files beyond MaxBackups.
+// Only files named as rotate() names them are considered, so unrelated files sharing the prefix are left alone.
+func (rf *RotatingFile) pruneBackups() error {
+	if rf.opts.MaxBackups <= 0 {
+		return nil
+	}
+	dir, base := filepath.Split(rf.pathname)
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var backups []string
+	prefix := base + "."
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		suffix := name[len(prefix):]
+		if len(suffix) != len(rotatedSuffix) {
+			continue
+		}
+		if _, err := time.Parse(rotatedSuffix, suffix); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, name))
+	}
+
+	// Timestamp suffixes sort chronologically
+	sort.Strings(backups)
+	for len(backups) > rf.opts.MaxBackups {
+		os.Remove(backups[0])
+		backups = backups[1:]
+	}
+	return nil
+}
+
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
@@ -0,0 +1,87 @@
+package log_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arcspace/go-cedar/log"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	pathname := filepath.Join(dir, "test.log")
+
+	rf, err := log.NewRotatingFile(pathname, log.RotatingFileOpts{
+		MaxSize:    10,
+		MaxBackups: 2,
+	})
+	require.NoError(t, err)
+	defer rf.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := rf.Write([]byte("12345678\n"))
+		require.NoError(t, err)
+		time.Sleep(2 * time.Millisecond) // ensure distinct rotation timestamps
+	}
+
+	backups, _ := filepath.Glob(pathname + ".*")
+	require.Len(t, backups, 2)
+
+	current, err := os.ReadFile(pathname)
+	require.NoError(t, err)
+	require.Equal(t, "12345678\n", string(current))
+}
+
+func TestRotatingFilePrunesOnlyBackups(t *testing.T) {
+	dir := t.TempDir()
+	pathname := filepath.Join(dir, "test.log")
+
+	// Files that merely share the log file's prefix aren't backups
+	others := []string{pathname + ".bak", pathname + ".1", pathname + ".20060102-150405.000.gz"}
+	for _, other := range others {
+		require.NoError(t, os.WriteFile(other, []byte("keep"), 0644))
+	}
+
+	rf, err := log.NewRotatingFile(pathname, log.RotatingFileOpts{
+		MaxBackups: 1,
+	})
+	require.NoError(t, err)
+	defer rf.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err := rf.Write([]byte("entry\n"))
+		require.NoError(t, err)
+		require.NoError(t, rf.Rotate())
+		time.Sleep(2 * time.Millisecond) // ensure distinct rotation timestamps
+	}
+
+	for _, other := range others {
+		require.FileExists(t, other)
+	}
+	backups, _ := filepath.Glob(pathname + ".2*-*.???")
+	require.Len(t, backups, 1)
+}
+
+func TestRotatingFileRotateFails(t *testing.T) {
+	dir := t.TempDir()
+	pathname := filepath.Join(dir, "test.log")
+
+	rf, err := log.NewRotatingFile(pathname, log.RotatingFileOpts{})
+	require.NoError(t, err)
+	defer rf.Close()
+
+	// Renaming fails once the file is gone, after which writes continue to a reopened file
+	require.NoError(t, os.Remove(pathname))
+	require.Error(t, rf.Rotate())
+
+	_, err = rf.Write([]byte("after\n"))
+	require.NoError(t, err)
+
+	current, err := os.ReadFile(pathname)
+	require.NoError(t, err)
+	require.Equal(t, "after\n", string(current))
+}
@@ -0,0 +1,152 @@
+// Package admin serves a JSON-over-HTTP admin API for managing a live process.Context tree.
+//
+// Each operation acts on a target Context, which is the root unless the query param "id" (see Task.RegisterIDs and
+// process.LookupID) or "path" (see process.FindByPath) selects a descendant:
+//
+//	GET  /tree                   TreeSnapshot of the target
+//	GET  /stats                  Stats of the target
+//	GET  /health                 HealthReport of the target
+//	POST /loglevel?level=debug   Sets the target's log level (inherited by descendants that don't set their own)
+//	POST /close                  Closes the target (and so its subtree)
+//	POST /reload                 Calls Reload() on the target
+//
+// For example, to mount alongside other debug endpoints:
+//
+//	http.Handle("/admin/", http.StripPrefix("/admin", admin.Handler(root)))
+//
+// Since these operations can close or reconfigure a running process, this should only be served on a private or
+// authenticated listener.
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/arcspace/go-cedar/log"
+	"github.com/arcspace/go-cedar/process"
+)
+
+// Handler returns an http.Handler serving the admin API for the given root and its descendants.
+func Handler(root process.Context) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/tree", op(root, http.MethodGet, func(target process.Context, r *http.Request) (any, error) {
+		return process.TreeSnapshot(target), nil
+	}))
+	mux.Handle("/stats", op(root, http.MethodGet, func(target process.Context, r *http.Request) (any, error) {
+		return target.Stats(), nil
+	}))
+	mux.Handle("/health", op(root, http.MethodGet, func(target process.Context, r *http.Request) (any, error) {
+		return target.HealthReport(), nil
+	}))
+	mux.Handle("/loglevel", op(root, http.MethodPost, func(target process.Context, r *http.Request) (any, error) {
+		level, err := log.ParseLevel(r.URL.Query().Get("level"))
+		if err != nil {
+			return nil, badRequest{err}
+		}
+		target.SetLogLevel(level)
+		return result(target, "log level set to "+level.String()), nil
+	}))
+	mux.Handle("/close", op(root, http.MethodPost, func(target process.Context, r *http.Request) (any, error) {
+		target.Close()
+		return result(target, "closing"), nil
+	}))
+	mux.Handle("/reload", op(root, http.MethodPost, func(target process.Context, r *http.Request) (any, error) {
+		if err := target.Reload(); err != nil {
+			return nil, err
+		}
+		return result(target, "reloaded"), nil
+	}))
+	return mux
+}
+
+// Result is the response to operations that don't return a snapshot.
+type Result struct {
+	ID     int64  `json:"id"`
+	Path   string `json:"path"` // ContextPath() of the target
+	Status string `json:"status"`
+}
+
+func result(target process.Context, status string) Result {
+	return Result{
+		ID:     target.ContextID(),
+		Path:   target.ContextPath(),
+		Status: status,
+	}
+}
+
+// badRequest marks an error as being the caller's fault.
+type badRequest struct {
+	error
+}
+
+// errNotFound is returned when the target Context does not exist (or is not within root).
+var errNotFound = errors.New("no such context")
+
+// op returns an http.Handler that resolves the target of a request and responds with what fn returns as JSON.
+func op(root process.Context, method string, fn func(target process.Context, r *http.Request) (any, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			writeJSON(w, http.StatusMethodNotAllowed, errorBody(errors.New(r.URL.Path+" requires "+method)))
+			return
+		}
+		target, err := resolve(root, r)
+		if err == nil {
+			var resp any
+			if resp, err = fn(target, r); err == nil {
+				writeJSON(w, http.StatusOK, resp)
+				return
+			}
+		}
+
+		status := http.StatusInternalServerError
+		var badReq badRequest
+		switch {
+		case errors.Is(err, errNotFound):
+			status = http.StatusNotFound
+		case errors.As(err, &badReq):
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, errorBody(err))
+	})
+}
+
+// resolve returns the Context selected by the given request's "id" or "path" query param, defaulting to root.
+func resolve(root process.Context, r *http.Request) (process.Context, error) {
+	query := r.URL.Query()
+	if idStr := query.Get("id"); idStr != "" {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			return nil, badRequest{errors.New("invalid id")}
+		}
+		target := process.LookupID(id)
+		if target == nil || !process.IsDescendant(target, root) {
+			return nil, errNotFound
+		}
+		return target, nil
+	}
+	if path := query.Get("path"); path != "" {
+		target := process.FindByPath(root, path)
+		if target == nil {
+			return nil, errNotFound
+		}
+		return target, nil
+	}
+	return root, nil
+}
+
+func errorBody(err error) any {
+	return struct {
+		Error string `json:"error"`
+	}{err.Error()}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(body)
+}
@@ -0,0 +1,65 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arcspace/go-cedar/log"
+	"github.com/arcspace/go-cedar/process"
+	"github.com/arcspace/go-cedar/process/admin"
+)
+
+func TestAdmin(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root", RegisterIDs: true})
+	defer p.Close()
+	server, _ := p.StartChild(&process.Task{Label: "server"})
+	session, _ := server.StartChild(&process.Task{
+		Label:    "session",
+		OnReload: func(ctx process.Context) error { return errors.New("bad config") },
+	})
+
+	srv := httptest.NewServer(admin.Handler(p))
+	defer srv.Close()
+
+	do := func(method, op string, query url.Values, out any) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+op+"?"+query.Encode(), nil)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if out != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	var tree process.ContextNode
+	require.Equal(t, http.StatusOK, do("GET", "/tree", url.Values{"path": {"server"}}, &tree))
+	require.Equal(t, "server", tree.Label)
+	require.Len(t, tree.Children, 1)
+
+	var stats process.Stats
+	require.Equal(t, http.StatusOK, do("GET", "/stats", nil, &stats))
+	require.Equal(t, 1, stats.ChildCount)
+
+	byID := url.Values{"id": {strconv.FormatInt(session.ContextID(), 10)}}
+	require.Equal(t, http.StatusOK, do("POST", "/loglevel", url.Values{"path": {"server"}, "level": {"debug"}}, nil))
+	require.Equal(t, log.Debug, server.GetLogLevel())
+	require.Equal(t, http.StatusBadRequest, do("POST", "/loglevel", url.Values{"level": {"loud"}}, nil))
+	require.Equal(t, http.StatusMethodNotAllowed, do("GET", "/close", byID, nil))
+	require.Equal(t, http.StatusInternalServerError, do("POST", "/reload", byID, nil))
+	require.Equal(t, http.StatusNotFound, do("GET", "/tree", url.Values{"path": {"nope"}}, nil))
+
+	var res admin.Result
+	require.Equal(t, http.StatusOK, do("POST", "/close", byID, &res))
+	require.Equal(t, session.ContextPath(), res.Path)
+	<-session.Done()
+	require.Equal(t, http.StatusNotFound, do("POST", "/close", byID, nil))
+}
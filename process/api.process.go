@@ -15,6 +15,17 @@ func Start(task *Task) (Context, error) {
 	return NilContext.StartChild(task)
 }
 
+// StartWithContext starts the given Task as its own process root that closes once the given context.Context is done.
+// This is equivalent to calling Start() with Task.Context set to the given context.Context.
+func StartWithContext(parent context.Context, task *Task) (Context, error) {
+	t := Task{}
+	if task != nil {
+		t = *task
+	}
+	t.Context = parent
+	return Start(&t)
+}
+
 func Go(parent Context, label string, fn func(ctx Context)) (Context, error) {
 	return parent.StartChild(&Task{
 		Label: label,
@@ -29,12 +40,141 @@ type Task struct {
 	// Note how this will not enter into effect unless OnRun is given or a child is started.
 	IdleClose time.Duration
 
+	// If > 0, an idle close (see CloseWhenIdle) never takes effect until this Context has been open at least this long,
+	// so that a brief idle window right after starting (e.g. while a session is still handshaking) doesn't reap it.
+	IdleCloseMin time.Duration
+
+	// If set, called when this Context is about to idle-close, where returning true defers the close as if Touch() were called
+	// (so the idle delay starts again).  This allows a Context to account for activity that isn't tracked via children or holds.
+	OnIdle func(ctx Context) (active bool)
+
+	// If > 0, OnRunErr is re-invoked up to this many additional times while it returns an error.
+	// Between attempts, the Context remains open (with the same ContextID) and a retry is abandoned if Closing() fires.
+	// If the final attempt still fails (or an error marked via Permanent() is returned), the Context is closed.
+	RunRetries         int
+	RunRetryBackoff    time.Duration // Delay before the first retry, doubling after each subsequent failed attempt.
+	RunRetryMaxBackoff time.Duration // Caps the delay between retries; if < RunRetryBackoff, 64x RunRetryBackoff is used.
+	RunRetryJitter     float64       // Fraction (0..1) that each retry delay is randomly adjusted by.
+
+	// If > 0, each OnRunErr attempt is passed its own child Context ("attempt") that closes with context.DeadlineExceeded
+	// after this long, where an attempt that times out fails with context.DeadlineExceeded (and so is retried as above).
+	RunRetryTimeout time.Duration
+
+	// If StartRetry.Retries > 0, StartChild() retries a failing OnStart (blocking) before giving up and returning its error.
+	// Between attempts, the Context remains open (with the same ContextID) and retrying is abandoned if Closing() fires.
+	StartRetry StartRetryPolicy
+
+	// If set, the parent automatically starts a new instance of this Task after this Context closes (as a new Context).
+	// Restarts are abandoned once the parent is closing, and while a restart is pending, the parent reports Degraded health.
+	RestartPolicy     RestartPolicy
+	MaxRestarts       int           // If > 0, caps how many times this Task is restarted.
+	RestartBackoff    time.Duration // Delay before the first restart, doubling after each subsequent restart.
+	RestartMaxBackoff time.Duration // Caps the delay between restarts; if < RestartBackoff, 64x RestartBackoff is used.
+
+	// Specifies how children are signaled to close when this Context closes.
+	// Use CloseOrder_LIFO when later-started children depend on earlier-started siblings.
+	CloseOrder CloseOrder
+
+	// When the parent closes, its children are signaled to close in batches of equal ClosePriority (highest first),
+	// where each batch is Done before the next is signaled (and within a batch, the parent's CloseOrder applies).
+	// For example, ingress listeners (2) can close before pipelines (1), which close before storage flushers (0).
+	ClosePriority int
+
+	// If > 0 and this Context has not reached Done() this long after Close(), a warning listing the descendants
+	// that are still closing (along with a goroutine dump) is logged and then OnCloseDeadline is called (if set).
+	// For root Contexts, DefaultCloseDeadline is used if this is 0.
+	CloseDeadline   time.Duration
+	OnCloseDeadline func(ctx Context, stuck []Context)
+
+	// If > 0, this is how long after Close() that OnRun is waited on before it is considered abandoned.
+	// Once abandoned, this Context proceeds to Done() (once its children are closed) without waiting for OnRun to return.
+	// If 0, Done() is always gated by OnRun returning.
+	AbandonRunAfter time.Duration
+
+	// If set, this Task can be started on a parent that is closing (e.g. from its OnClosing) to perform cleanup, and the parent's Done()
+	// waits for it.  A Cleanup child is not signaled to close when its parent closes, so it must close on its own (e.g. via IdleClose once OnRun returns).
+	// Cleanup children can be started (e.g. from OnClosing or a still-running OnRun) until the parent starts waiting on its children and OnRun,
+	// after which ErrClosing is returned.
+	Cleanup bool
+
+	// If set, this Context is started as a child of the root of the parent's tree (rather than of the parent), so that it
+	// outlives the parent (e.g. for a final flush or cleanup).  It still inherits the parent's Values, trace ID, and log level.
+	Detached bool
+
+	// If set, this Context and all its descendants are registered by ContextID() while open so that they can be found via LookupID().
+	// This is typically set on a root so that admin tooling (e.g. the debug handler) can act on a specific Context.
+	RegisterIDs bool
+
+	// The Blueprint this Task was started from (set by StartBlueprint), which ExportBlueprint() consults for its Kind and dependencies.
+	Blueprint *Blueprint
+
+	// If set, this Context and all its descendants run OnRun (and OnRunErr) with runtime/pprof goroutine labels
+	// "cedar_ctx" (see ContextPath) and "cedar_id" (see ContextID), so goroutine profiles and traces can be grouped by Context.
+	// Typically set on a root since rendering the labels adds overhead to each Context that has an OnRun.
+	ProfileLabels bool
+
+	// If > 0, this Context retains its most recent HistorySize lifecycle events and log entries (including entries not emitted
+	// due to the log level), retrievable via History() and included in tree dumps.  If 0, the parent's HistorySize is used.
+	HistorySize int
+
+	// If set, this Context and its descendants (unless they set their own) use this Clock rather than the parent's (or for roots, see SetClock).
+	Clock Clock
+
+	// If set, this Context is closed once this context.Context is done, with its Err() as the cause (see CloseWithError).
+	// This allows process trees to live inside of HTTP handlers, gRPC calls, and tests that carry a context.Context.
+	Context context.Context
+
+	// If set, this Context's trace ID (see TraceID), otherwise the trace ID of its parent (or of Context) is inherited.
+	TraceID string
+
+	// If either is set, this Context (and so its subtree) is closed with context.DeadlineExceeded once the deadline is reached.
+	// A Context's effective deadline is the earliest of Deadline, its start time plus Timeout, and its parent's deadline,
+	// which is what Deadline() reports to downstream libraries.
+	Deadline time.Time
+	Timeout  time.Duration
+
+	// Initial values for this Context, which are visible to Value() on this Context and its descendants.
+	Values map[any]any
+
 	TaskRef   any                     // Offered to you for open-ended use.
+	Tags      map[string]string       // Metadata for tooling (e.g. "component": "ingest"), surfaced in tree dumps, metrics, and WithTag(); not modified once started.
+	Critical  bool                    // If set, this Context being unhealthy or down (including once closed, until restarted) makes its parent Unhealthy (rather than Degraded).
+	Owner     any                     // If non-nil, identifies the owner (e.g. tenant) of this Context; must be comparable (a comparable type holding only comparable values). See ForEachByOwner().
 	Label     string                  // Label is a log label and debugging
+	LabelArgs []any                   // If set, Label is a fmt format rendered with these args when Label() is first called
+	Unique    bool                    // If set, StartChild() returns the parent's open child having the same Label along with ErrAlreadyRunning (rather than starting another).
 	OnStart   func(ctx Context) error // Blocking fn called in StartChild(). If err, ctx.Close() is called and Go() returns the err and OnRun is never called.
 	OnRun     func(ctx Context)       // Async work body. If non-nil, ctx.Close() will be automatically called after OnRun() completes
+	OnRunErr  func(ctx Context) error // Alternative to OnRun where a returned error signals a failed run (see RunRetries). Ignored if OnRun is set.
 	OnClosing func()                  // Called after Close() is first called and immediately before children are signaled to close.
 	OnClosed  func()                  // Called after Close() and all children have completed Close() (but immediately before Done() is released)
+
+	// Called if OnStart or OnRun panics, after which ctx is closed with a *PanicError as its Cause().
+	// If nil, DefaultOnPanic is used.
+	OnPanic func(ctx Context, recovered any, stack []byte)
+
+	// If set and chaos mode is enabled (see EnableChaos, available with -tags chaos), this Task's callbacks panic at random.
+	ChaosPanics bool
+
+	// If set, called by HealthReport() on this Context or an ancestor, where a returned error makes this Context Unhealthy in the report.
+	// This should return promptly (e.g. by pinging a dependency with a short timeout).
+	HealthCheck func(ctx Context) error
+
+	// Called on this Context as each of its children is started (immediately before the child's OnStart) and then again once that
+	// child has closed (immediately before the child's Done() is released), where err is the child's Err() cause (nil if it was closed normally).
+	// These may be called concurrently since children start and close independently.
+	OnChildStart  func(child Context)
+	OnChildClosed func(child Context, err error)
+
+	// Called with each message passed to Broadcast() on an ancestor of this Context (e.g. a config reload or flush command).
+	// If this panics, this Context is closed with a *PanicError as its Cause() (as with OnStart and OnRun).
+	OnMessage func(ctx Context, msg any)
+
+	// Called by Reload() on this Context or an ancestor, typically to re-read configuration.
+	// A returned error (or panic) is reported by Reload() but otherwise leaves this Context open.
+	OnReload func(ctx Context) error
+
+	runAt time.Time // set by GoAt()
 }
 
 type Context interface {
@@ -43,17 +183,69 @@ type Context interface {
 	// A process.Context is an extension of context.Context.
 	context.Context
 
-	// Returns Task.Ref passed into StartChild()
+	// Returns Task.TaskRef passed into StartChild() or the value last passed to SetTaskRef() (see also TaskRefAs).
 	TaskRef() interface{}
 
+	// Replaces the value returned by TaskRef(), e.g. once the object a Context represents has been constructed in OnStart.
+	// This is safe to call concurrently with TaskRef(), where callers of TaskRef() see either the previous or the new value.
+	SetTaskRef(ref any)
+
+	// Returns Task.Owner passed into StartChild()
+	Owner() interface{}
+
+	// Returns Task.Tags passed into StartChild(), which must not be modified.
+	Tags() map[string]string
+
+	// Associates the given value with key on this Context, making it visible to Value() on this Context and its descendants.
+	// Key conventions follow context.WithValue().
+	SetValue(key, val interface{})
+
+	// Returns the Limiter having the given name on this Context or its nearest ancestor having one, else creates it on this Context,
+	// allowing rate events per second with bursts of up to burst (where rate <= 0 means unlimited).
+	// Like Value(), this allows a subtree to share a Limiter, whose Wait() aborts once the Context it was created on is closing.
+	Limiter(name string, rate float64, burst int) *Limiter
+
+	// Like Limiter() but for a Semaphore allowing up to n concurrent holders.
+	Semaphore(name string, n int) *Semaphore
+
 	// The context's public label
 	Label() string
 
+	// Returns the '/' separated chain of "label#id" from this Context's root down to this Context, e.g. "root#1/session#4/reader#7".
+	// Unlike Label(), this distinguishes like-labelled Contexts in different subtrees (see also LogPaths).
+	ContextPath() string
+
+	// Sets the trace ID for this Context, which is included in its log output and inherited by children started hereafter.
+	// This is safe to call while this Context is in use, though to start a child with a trace ID, see WithTraceID().
+	SetTraceID(traceID string)
+
+	// Returns this Context as seen by children started via it, which are given the trace ID (as with Task.TraceID), e.g.
+	// p.WithTraceID(id).StartChild(task).  Unlike SetTraceID, this Context's own trace ID is unchanged, so a shared parent can
+	// start each request's subtree with that request's trace ID.  Other methods of the returned Context act on this Context.
+	WithTraceID(traceID string) Context
+
+	// Returns the trace ID set via SetTraceID() or the trace ID inherited when this Context was started, either from its parent
+	// or from Task.Context (see ContextWithTraceID).
+	TraceID() string
+
+	// Returns this Context's current state: Starting (while OnStart runs), Running, Closing, or Closed (see Close for when each is entered).
+	State() int32
+
+	// Returns when this Context was started.
+	StartTime() time.Time
+
+	// Returns this Context's lifecycle timings and child counts as of now.
+	Stats() Stats
+
+	// Returns this Context's retained lifecycle events and log entries, oldest first (or nil if Task.HistorySize is not set).
+	History() []HistoryEntry
+
 	// A guaranteed unique ID assigned after Start() is called.
 	ContextID() int64
 
 	// Creates a new child Context with for given Task.
 	// If OnStart() returns an error error is encountered, then child.Close() is immediately called and the error is returned.
+	// If this Context is closing (or draining), ErrClosing is returned (unless Task.Cleanup is set), and once closed, ErrClosed is returned.
 	StartChild(task *Task) (Context, error)
 
 	// Convenience function for StartChild() and is equivalent to:
@@ -64,26 +256,172 @@ type Context interface {
 	//      })
 	Go(label string, fn func(ctx Context)) (Context, error)
 
+	// Starts a child Context that calls fn once the given delay has passed (or never if it is closed first).
+	// Until then, the child reports "scheduled" in TreeSnapshot().  Like Go(), the child closes once fn returns.
+	GoAfter(delay time.Duration, label string, fn func(ctx Context)) (Context, error)
+
+	// Like GoAfter() but fn is called at the given time.
+	GoAt(at time.Time, label string, fn func(ctx Context)) (Context, error)
+
+	// Starts a child Context that calls fn every interval until it is closed, skipping runs that come due while fn is still running.
+	// See GoScheduled() for cron schedules and other overlap policies.
+	GoPeriodic(label string, interval time.Duration, fn func(ctx Context)) (Context, error)
+
+	// errgroup-style equivalent of Go() where fn returns an error.
+	// The first child to return a non-nil error causes this Context to be closed via CloseWithError() with that error.
+	GoErr(label string, fn func(ctx Context) error) (Context, error)
+
+	// Blocks until all children started via GoErr() have returned, then returns the first non-nil error they returned (if any).
+	Wait() error
+
+	// Records err (wrapped in a *ContextError with this Context's path) so that it is returned by Errors() on this Context and its ancestors,
+	// including after this Context closes.  Errors returned by GoErr() bodies are reported automatically.
+	ReportError(err error)
+
+	// Returns the errors reported via ReportError() by this Context and its descendants (open or closed), oldest first within each Context.
+	// At most MaxReportedErrors are retained per Context, with the oldest dropped, so a long-lived root can't accumulate errors without bound.
+	Errors() []error
+
+	// Blocks for the given duration (per this Context's Clock) and returns true, or returns false as soon as Closing() fires.
+	Sleep(d time.Duration) bool
+
+	// Returns a channel that receives the time every interval until Closing() fires, after which the channel is closed.
+	// Like a time.Ticker, ticks are dropped if the receiver falls behind.
+	Tick(interval time.Duration) <-chan time.Time
+
+	// Calls fn in its own goroutine once the given duration has passed, unless Closing() fires first.
+	// While fn is pending (or running), this Context won't idle-close.  stop() cancels fn, returning false if fn was already called or cancelled.
+	AfterFunc(d time.Duration, fn func()) (stop func() bool)
+
 	// Appends all currently open/active child Contexts to the given slice and returns the given slice.
 	// Naturally, the returned items are back-ward looking as any could close at any time.
 	// Context implementations wishing to remain lightweight may opt to not retain a list of children (and just return the given slice as-is).
-	GetChildren(in []Context) []Context
+	//
+	// Options filter the children returned or extend the search to all descendants, e.g. GetChildren(nil, WithRecursive(), WithLabelPrefix("session/")).
+	// This avoids copying every child of a large tree only to filter them in the caller.
+	GetChildren(in []Context, opts ...ChildOption) []Context
+
+	// Returns the oldest open child Context having the given Label(), or nil if there is none.
+	// See FindByPath() to address a Context further down the tree.
+	GetChild(label string) Context
+
+	// Blocks until all children having any of the given labels (or if none are given, all children) that are open when called are Done.
+	// Children started during the call are not waited on.  See Join() to wait on an arbitrary set of Contexts.
+	WaitChildren(labels ...string)
+
+	// Transfers this (running) Context and its subtree to become a child of newParent, so that it is listed by and closed with newParent.
+	// Its Values, trace ID, log level, and deadline are those it was started with (and not re-derived from newParent).
+	// Returns ErrClosing if either this Context or newParent is closing (or draining), and ErrBadParent if this Context is a root
+	// or if newParent is this Context or one of its descendants.
+	MoveTo(newParent Context) error
 
 	// Async call that initiates process shutdown and causes all children's Close() to be called.
 	// Close can be called multiple times but calls after the first are in effect ignored.
-	// First, child processes get Close() in breath-first order.
-	// After all children are done closing, OnClosing(), then OnClosed() are executed.
+	//
+	// The close sequence guarantees the following order, each step happening strictly after the one before:
+	//  1. State() reports Closing and Closing() fires, then callbacks registered via OnClosing() are called
+	//  2. OnClosing is called
+	//  3. Children are signaled to close (as specified by Task.CloseOrder and Task.ClosePriority), so their Closing() fires
+	//  4. All descendants reach Done() and OnRun (if set) returns (see AbandonRunAfter)
+	//  5. State() reports Closed and OnClosed is called
+	//  6. The parent's OnChildClosed (if set) is called
+	//  7. Done() fires, then callbacks registered via OnDone() are called
 	Close() error
 
+	// Calls Close() and blocks until this Context is Done() or until timeout elapses (where timeout <= 0 waits indefinitely).
+	// On timeout, a *CloseTimeoutError is returned listing the ContextPath() of each descendant still closing.
+	CloseAndWait(timeout time.Duration) error
+
+	// Stops accepting new children (StartChild() returns ErrClosing) while existing children continue to run.
+	// Once the last child closes (or immediately if there are none), this Context is closed.
+	// Calling Close() while draining closes this Context (and its children) as usual.
+	Drain()
+
+	// Pauses this Context and its descendants (including those started while paused) until Resume() is called on this Context.
+	// Pausing is cooperative: it has no effect on a Context until it calls Checkpoint(), so well-behaved OnRun loops should call
+	// Checkpoint() between units of work.  This allows a subtree to be quiesced (e.g. during a migration) without closing it.
+	Pause()
+
+	// Resumes this Context if it was paused via Pause(), releasing any Checkpoint() calls blocked on it.
+	// Descendants remain paused if they (or another ancestor) are also paused.
+	Resume()
+
+	// Returns true if this Context or any of its ancestors is paused.
+	IsPaused() bool
+
+	// Blocks while this Context is paused (see Pause), returning ErrClosing once this Context is closing (even if not paused),
+	// allowing OnRun loops to be written as:
+	//
+	//	for ctx.Checkpoint() == nil {
+	//	    ...
+	//	}
+	Checkpoint() error
+
+	// Signals that this Context's work completed successfully and then calls Close().
+	// Typically called from OnRun so that CloseKind() reports CloseKind_Completed rather than CloseKind_Cancelled.
+	Complete()
+
+	// Reports why this Context closed (or CloseKind_Open if Close() has not yet been called).
+	// See CloseKind for how precedence is determined.
+	CloseKind() CloseKind
+
+	// Like Close() but records err as the reason this Context (and its children) closed.
+	// Once Done(), Err() returns err, making it also compatible with context.Cause().
+	// The first Close() (or CloseWithError) determines the cause; if err is nil, this is equivalent to Close().
+	CloseWithError(err error) error
+
+	// Returns nil until Closing() fires, then returns the error passed to CloseWithError() (or context.Canceled if none was given).
+	// Unlike Err(), this is available during OnClosing so that children can observe why their parent closed.
+	Cause() error
+
 	// Inserts a pending Close() on this Context once it is idle after the given delay.
 	// Subsequent calls will update the delay but the previously pending delay must run out first.
 	// If at the end of the period Task.OnRun() is complete and there are no children, then Close() is called.
 	CloseWhenIdle(delay time.Duration)
 
-	// Signals when Close() has been called.
-	// First, Child processes get Close(),  then OnClosing, then OnClosed are executing
+	// Prevents this Context from idle-closing until the returned release func is called (which is safe to call more than once).
+	// This bridges the gap between deciding to start more work and actually starting it without an idle close slipping in.
+	// Outstanding holds are automatically released once Close() is called, so a hold never blocks Done().
+	HoldIdle() (release func())
+
+	// Records activity on this Context, restarting any pending idle close delay (see CloseWhenIdle).
+	// For example, a connection handler can call this for each request so that only sessions without recent traffic are reaped.
+	// Use HoldIdle() instead to keep this Context open for the duration of some work.
+	Touch()
+
+	// Synchronously delivers msg to the Task.OnMessage of each Running descendant of this Context that sets it, parents before children.
+	// Descendants still Starting (or already Closing) are skipped.
+	Broadcast(msg any)
+
+	// Synchronously calls the Task.OnReload of this Context and each of its Running descendants that sets it, parents before children.
+	// If any fail, a *ReloadError listing each failure is returned.  See also ReloadOnSignal().
+	Reload() error
+
+	// Flags this Context as healthy or not (Contexts start healthy); see Health().
+	SetHealthy(healthy bool)
+
+	// Aggregates the health of this Context and all its descendants:
+	//   - Healthy if this Context and all descendants are healthy,
+	//   - Unhealthy if this Context is unhealthy or a Task.Critical child is unhealthy or closing, otherwise
+	//   - Degraded if any descendant is unhealthy, is pending a child restart, or is flapping (see FlapThreshold).
+	Health() HealthStatus
+
+	// Like Health() but also runs the Task.HealthCheck of this Context and its descendants (concurrently), returning the status,
+	// check error, and check latency of each, aggregated as with Health().  See HealthHandler() and ReadyHandler().
+	HealthReport() HealthReport
+
+	// Signals when Close() has been called, before OnClosing is called and before any children are signaled to close.
+	// Once this fires, State() no longer reports Running and Cause() reports why this Context is closing.
 	Closing() <-chan struct{}
 
-	// Signals when Close() has fully executed, no children remain, and OnClosed() has been completed.
+	// Signals when Close() has fully executed: all descendants are Done(), OnRun has returned, and OnClosed has returned.
 	Done() <-chan struct{}
+
+	// Arranges for fn to be called once Closing() has fired (before Task.OnClosing is called), or calls it immediately if it already has.
+	// Unlike selecting on Closing(), this doesn't require a goroutine per watcher, so fn is called from the goroutine running this
+	// Context's close sequence and must not block.  The returned cancel func prevents fn from being called if it has not been already.
+	OnClosing(fn func()) (cancel func())
+
+	// Like OnClosing() but for Done(), where fn is called once Done() has fired.
+	OnDone(fn func()) (cancel func())
 }
@@ -0,0 +1,78 @@
+package process_test
+
+import (
+	"testing"
+
+	"github.com/arcspace/go-cedar/process"
+)
+
+// BenchmarkStartChild measures starting and closing a child Context with no callbacks.
+func BenchmarkStartChild(b *testing.B) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		child, err := p.StartChild(&process.Task{Label: "child"})
+		if err != nil {
+			b.Fatal(err)
+		}
+		child.Close()
+		<-child.Done()
+	}
+}
+
+// BenchmarkStartChildParallel measures heavy start/close churn of siblings sharing a parent.
+func BenchmarkStartChildParallel(b *testing.B) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			child, err := p.StartChild(&process.Task{Label: "child"})
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			child.Close()
+			<-child.Done()
+		}
+	})
+}
+
+// BenchmarkManyChildren measures closing children out of start order while many siblings remain open.
+func BenchmarkManyChildren(b *testing.B) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	const N = 10000
+	children := make([]process.Context, N)
+	for i := range children {
+		children[i], _ = p.StartChild(&process.Task{Label: "child"})
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		j := (i * 7919) % N
+		children[j].Close()
+		<-children[j].Done()
+		children[j], _ = p.StartChild(&process.Task{Label: "child"})
+	}
+}
+
+// BenchmarkGo measures Go(), which adds an OnRun goroutine and idle close to each child.
+func BenchmarkGo(b *testing.B) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		child, err := p.Go("child", func(ctx process.Context) {})
+		if err != nil {
+			b.Fatal(err)
+		}
+		<-child.Done()
+	}
+}
@@ -0,0 +1,197 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Blueprint is a serializable description of a Task and its children (but not of any runtime state), allowing service
+// composition to be described declaratively (e.g. in JSON) and a subtree to be rebuilt (e.g. after a supervised crash).
+//
+// Since callbacks can't be serialized, a Blueprint names a Kind whose TaskFactory (see RegisterKind) supplies them.
+// A Blueprint having no Kind is started as a plain Task (e.g. a group for its children).
+type Blueprint struct {
+	Kind          string            `json:"kind,omitempty"`
+	Label         string            `json:"label"`
+	Tags          map[string]string `json:"tags,omitempty"`
+	Config        map[string]string `json:"config,omitempty"`     // Kind-specific settings for use by its TaskFactory
+	DependsOn     []string          `json:"depends_on,omitempty"` // Labels of siblings that StartBlueprint() starts before this one
+	Critical      bool              `json:"critical,omitempty"`
+	Unique        bool              `json:"unique,omitempty"`
+	IdleClose     time.Duration     `json:"idle_close,omitempty"`
+	CloseOrder    CloseOrder        `json:"close_order,omitempty"`
+	ClosePriority int               `json:"close_priority,omitempty"`
+
+	RestartPolicy     RestartPolicy `json:"restart_policy,omitempty"`
+	MaxRestarts       int           `json:"max_restarts,omitempty"`
+	RestartBackoff    time.Duration `json:"restart_backoff,omitempty"`
+	RestartMaxBackoff time.Duration `json:"restart_max_backoff,omitempty"`
+
+	Children []Blueprint `json:"children,omitempty"`
+}
+
+// TaskFactory returns a new Task supplying the callbacks for the given Blueprint, whose Label, Tags, and other
+// declarative fields are then applied to the returned Task by StartBlueprint().
+type TaskFactory func(bp *Blueprint) (*Task, error)
+
+// ErrBadBlueprint is returned by StartBlueprint() if a Blueprint names an unregistered Kind or has unsatisfiable dependencies.
+var ErrBadBlueprint = errors.New("invalid blueprint")
+
+var gKinds = struct {
+	sync.RWMutex
+	byName map[string]TaskFactory
+}{
+	byName: make(map[string]TaskFactory),
+}
+
+// RegisterKind registers the TaskFactory used to start Blueprints of the given Kind, replacing any previously registered.
+func RegisterKind(kind string, factory TaskFactory) {
+	gKinds.Lock()
+	gKinds.byName[kind] = factory
+	gKinds.Unlock()
+}
+
+// ExportBlueprint returns the Blueprint of the given Context and its descendants as currently running.
+// Kind, Config, and DependsOn are only known for Contexts started via StartBlueprint().
+func ExportBlueprint(ci Context) Blueprint {
+	p, _ := asCtx(ci)
+	task := &p.task
+
+	bp := Blueprint{
+		Label:             p.Label(),
+		Tags:              task.Tags,
+		Critical:          task.Critical,
+		Unique:            task.Unique,
+		IdleClose:         task.IdleClose,
+		CloseOrder:        task.CloseOrder,
+		ClosePriority:     task.ClosePriority,
+		RestartPolicy:     task.RestartPolicy,
+		MaxRestarts:       task.MaxRestarts,
+		RestartBackoff:    task.RestartBackoff,
+		RestartMaxBackoff: task.RestartMaxBackoff,
+	}
+	if src := task.Blueprint; src != nil {
+		bp.Kind = src.Kind
+		bp.Config = src.Config
+		bp.DependsOn = src.DependsOn
+	}
+
+	var subBuf [20]Context
+	for _, child := range p.GetChildren(subBuf[:0]) {
+		bp.Children = append(bp.Children, ExportBlueprint(child))
+	}
+	return bp
+}
+
+// StartBlueprint starts the given Blueprint as a child of parent, followed by its children (recursively),
+// where each is started after the siblings named by its DependsOn.
+//
+// If any Task fails to start, the Contexts started so far are closed and waited on, and the error is returned.
+// Since dependents are started after their dependencies, CloseOrder_LIFO closes them in the reverse order.
+func StartBlueprint(parent Context, bp *Blueprint) (Context, error) {
+	task, err := bp.newTask()
+	if err != nil {
+		return nil, err
+	}
+	p, err := parent.StartChild(task)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", bp.Label, err)
+	}
+
+	order, err := dependencyOrder(bp.Children)
+	if err == nil {
+		for _, child := range order {
+			if _, err = StartBlueprint(p, child); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		p.Close()
+		<-p.Done()
+		return nil, fmt.Errorf("%s/%w", bp.Label, err)
+	}
+	return p, nil
+}
+
+// newTask returns a Task for this Blueprint from its Kind's TaskFactory (if any) with this Blueprint's fields applied.
+func (bp *Blueprint) newTask() (*Task, error) {
+	task := &Task{}
+	if bp.Kind != "" {
+		gKinds.RLock()
+		factory := gKinds.byName[bp.Kind]
+		gKinds.RUnlock()
+		if factory == nil {
+			return nil, fmt.Errorf("%s: %w: unknown kind %q", bp.Label, ErrBadBlueprint, bp.Kind)
+		}
+
+		var err error
+		if task, err = factory(bp); err != nil {
+			return nil, fmt.Errorf("%s: %w", bp.Label, err)
+		}
+	}
+
+	task.Label = bp.Label
+	task.LabelArgs = nil
+	task.Tags = bp.Tags
+	task.Critical = bp.Critical
+	task.Unique = bp.Unique
+	task.IdleClose = bp.IdleClose
+	task.CloseOrder = bp.CloseOrder
+	task.ClosePriority = bp.ClosePriority
+	task.RestartPolicy = bp.RestartPolicy
+	task.MaxRestarts = bp.MaxRestarts
+	task.RestartBackoff = bp.RestartBackoff
+	task.RestartMaxBackoff = bp.RestartMaxBackoff
+	task.Blueprint = bp
+	return task, nil
+}
+
+// dependencyOrder returns the given sibling Blueprints ordered so that each follows those named in its DependsOn,
+// otherwise preserving the given order.
+func dependencyOrder(siblings []Blueprint) ([]*Blueprint, error) {
+	byLabel := make(map[string]*Blueprint, len(siblings))
+	for i := range siblings {
+		byLabel[siblings[i].Label] = &siblings[i]
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make(map[*Blueprint]int, len(siblings))
+	order := make([]*Blueprint, 0, len(siblings))
+
+	var visit func(bp *Blueprint) error
+	visit = func(bp *Blueprint) error {
+		switch marks[bp] {
+		case visiting:
+			return fmt.Errorf("%s: %w: dependency cycle", bp.Label, ErrBadBlueprint)
+		case visited:
+			return nil
+		}
+		marks[bp] = visiting
+		for _, label := range bp.DependsOn {
+			dep := byLabel[label]
+			if dep == nil {
+				return fmt.Errorf("%s: %w: unknown dependency %q", bp.Label, ErrBadBlueprint, label)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		marks[bp] = visited
+		order = append(order, bp)
+		return nil
+	}
+
+	for i := range siblings {
+		if err := visit(&siblings[i]); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
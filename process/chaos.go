@@ -0,0 +1,107 @@
+//go:build chaos
+
+package process
+
+import (
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/arcspace/go-cedar/log"
+)
+
+// ChaosOpts specifies the faults injected while chaos mode is enabled (only available when built with -tags chaos).
+type ChaosOpts struct {
+	MaxDelay  time.Duration // Upper bound of the random delay injected before each OnStart and OnClosing (5ms if 0)
+	PanicRate float64       // Probability that each OnStart, OnRun, etc. of a Task having ChaosPanics set panics (0.1 if 0)
+	Seed      int64         // Seed for the injected faults, so a failing run can be reproduced (time-based if 0)
+}
+
+var gChaos struct {
+	sync.Mutex
+	enabled bool
+	opts    ChaosOpts
+	rand    *rand.Rand
+}
+
+// If the CEDAR_CHAOS environment variable is set, chaos mode is enabled at startup, where a numeric value is used as the seed.
+func init() {
+	if env, ok := os.LookupEnv("CEDAR_CHAOS"); ok {
+		seed, _ := strconv.ParseInt(env, 10, 64)
+		EnableChaos(ChaosOpts{Seed: seed})
+	}
+}
+
+// EnableChaos injects random delays into OnStart and OnClosing, randomizes the order in which children are signaled to close,
+// and simulates panics in Tasks having ChaosPanics set, until the returned func is called.
+// This is intended to shake out lifecycle races in tests run with -tags chaos.
+func EnableChaos(opts ChaosOpts) (disable func()) {
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = 5 * time.Millisecond
+	}
+	if opts.PanicRate <= 0 {
+		opts.PanicRate = 0.1
+	}
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+
+	gChaos.Lock()
+	gChaos.enabled = true
+	gChaos.opts = opts
+	gChaos.rand = rand.New(rand.NewSource(opts.Seed))
+	gChaos.Unlock()
+	log.NewLogger("chaos").Warnf("chaos mode enabled (seed %d)", opts.Seed)
+
+	return func() {
+		gChaos.Lock()
+		gChaos.enabled = false
+		gChaos.Unlock()
+	}
+}
+
+// chaosRand calls fn with the chaos random source if chaos mode is enabled, returning false if it is not.
+func chaosRand(fn func(r *rand.Rand, opts *ChaosOpts)) bool {
+	gChaos.Lock()
+	defer gChaos.Unlock()
+	if !gChaos.enabled {
+		return false
+	}
+	fn(gChaos.rand, &gChaos.opts)
+	return true
+}
+
+// chaosDelay sleeps for a random duration if chaos mode is enabled.
+func chaosDelay() {
+	var delay time.Duration
+	if chaosRand(func(r *rand.Rand, opts *ChaosOpts) {
+		delay = time.Duration(r.Int63n(int64(opts.MaxDelay)))
+	}) {
+		time.Sleep(delay)
+	}
+}
+
+// chaosShuffle randomizes the order of the given children if chaos mode is enabled.
+func chaosShuffle(children []Context) {
+	chaosRand(func(r *rand.Rand, opts *ChaosOpts) {
+		r.Shuffle(len(children), func(i, j int) {
+			children[i], children[j] = children[j], children[i]
+		})
+	})
+}
+
+// chaosPanic panics at random if chaos mode is enabled and the given Context's Task has ChaosPanics set.
+func chaosPanic(p *ctx) {
+	if !p.task.ChaosPanics {
+		return
+	}
+	fail := false
+	chaosRand(func(r *rand.Rand, opts *ChaosOpts) {
+		fail = r.Float64() < opts.PanicRate
+	})
+	if fail {
+		panic("chaos: simulated panic")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !chaos
+
+package process
+
+// Without -tags chaos, the chaos hooks compile away (see chaos.go).
+
+func chaosDelay()                     {}
+func chaosShuffle(children []Context) {}
+func chaosPanic(p *ctx)               {}
@@ -0,0 +1,46 @@
+//go:build chaos
+
+package process_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arcspace/go-cedar/process"
+	"github.com/arcspace/go-cedar/process/ptest"
+)
+
+func TestChaos(t *testing.T) {
+	disable := process.EnableChaos(process.ChaosOpts{
+		MaxDelay:  time.Millisecond,
+		PanicRate: 0.2,
+		Seed:      1,
+	})
+	defer disable()
+
+	quiet := func(ctx process.Context, recovered any, stack []byte) {}
+	for i := 0; i < 20; i++ {
+		p, _ := process.Start(&process.Task{Label: "root"})
+		for j := 0; j < 5; j++ {
+			svc, err := p.StartChild(&process.Task{
+				Label:       "svc",
+				ChaosPanics: true,
+				OnPanic:     quiet,
+				OnStart:     func(ctx process.Context) error { return nil },
+				OnClosing:   func() {},
+			})
+			if err != nil {
+				var panicErr *process.PanicError
+				require.ErrorAs(t, err, &panicErr)
+				continue
+			}
+			process.Go(svc, "worker", func(ctx process.Context) {
+				<-ctx.Closing()
+			})
+		}
+		p.Close()
+		ptest.RequireDoneWithin(t, p, 5*time.Second)
+	}
+}
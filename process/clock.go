@@ -0,0 +1,87 @@
+package process
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Clock is the source of time used by Contexts for IdleClose, deadlines, retry and restart backoff, and scheduling.
+// This allows tests to substitute a synthetic clock (see package ptest) and advance time rather than sleeping.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	AfterFunc(d time.Duration, fn func()) Timer
+}
+
+// Timer is the Clock equivalent of a *time.Timer.
+type Timer interface {
+	C() <-chan time.Time // nil for timers created via AfterFunc()
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// RealClock is the Clock backed by package time.
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, fn func()) Timer {
+	return realTimer{time.AfterFunc(d, fn)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+var gClock atomic.Value // clockHolder
+
+type clockHolder struct {
+	Clock
+}
+
+// SetClock sets the Clock used by root Contexts started from here forward that don't specify Task.Clock.
+// Passing nil restores RealClock.
+func SetClock(clock Clock) {
+	if clock == nil {
+		clock = RealClock
+	}
+	gClock.Store(clockHolder{clock})
+}
+
+func defaultClock() Clock {
+	if holder, ok := gClock.Load().(clockHolder); ok {
+		return holder.Clock
+	}
+	return RealClock
+}
+
+// clockFor returns the Clock for a new Context: its Task's Clock, else its parent's, else the default.
+func clockFor(task *Task, parent *ctx) Clock {
+	switch {
+	case task.Clock != nil:
+		return task.Clock
+	case parent != nil:
+		return parent.clock
+	}
+	return defaultClock()
+}
+
+// ClockOf returns the Clock used by the given Context.
+func ClockOf(ci Context) Clock {
+	if p, ok := asCtx(ci); ok && p != nil {
+		return p.clock
+	}
+	return defaultClock()
+}
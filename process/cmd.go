@@ -0,0 +1,121 @@
+package process
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// DefaultCmdGracePeriod is how long StartCmd() waits for a subprocess to exit after SIGTERM before killing it.
+var DefaultCmdGracePeriod = 5 * time.Second
+
+// CmdOpts specifies how StartCmd() supervises a subprocess.
+type CmdOpts struct {
+	GracePeriod time.Duration // Time allowed between SIGTERM and SIGKILL when the Context closes (DefaultCmdGracePeriod if 0)
+	LogOutput   bool          // If set, each line of stdout (or stderr) is logged to the Context's Info (or Warn) if cmd.Stdout (or cmd.Stderr) is nil
+}
+
+// StartCmd starts the given command as a child Context of parent, where opts may be nil.
+//
+// The child closes once the subprocess exits (and its own children, if any, have closed), where a failed exit (such as an *exec.ExitError) becomes the child's Cause().
+// Conversely, when the child closes, the subprocess is sent SIGTERM and is killed if it doesn't exit within CmdOpts.GracePeriod.
+// If cmd fails to start, the error from cmd.Start() is returned.
+func StartCmd(parent Context, label string, cmd *exec.Cmd, opts *CmdOpts) (Context, error) {
+	var o CmdOpts
+	if opts != nil {
+		o = *opts
+	}
+	if o.GracePeriod <= 0 {
+		o.GracePeriod = DefaultCmdGracePeriod
+	}
+
+	var stdout, stderr *logWriter
+
+	return parent.StartChild(&Task{
+		Label:     label,
+		IdleClose: time.Nanosecond,
+		OnStart: func(ctx Context) error {
+			if o.LogOutput {
+				if cmd.Stdout == nil {
+					stdout = &logWriter{log: func(line string) { ctx.Info(0, line) }}
+					cmd.Stdout = stdout
+				}
+				if cmd.Stderr == nil {
+					stderr = &logWriter{log: func(line string) { ctx.Warn(line) }}
+					cmd.Stderr = stderr
+				}
+			}
+			return cmd.Start()
+		},
+		OnRunErr: func(ctx Context) error {
+			chExited := make(chan struct{})
+			go func() {
+				select {
+				case <-chExited:
+				case <-ctx.Closing():
+					stopCmd(ctx, cmd.Process, o.GracePeriod, chExited)
+				}
+			}()
+
+			err := cmd.Wait()
+			close(chExited)
+			stdout.flush()
+			stderr.flush()
+
+			// An exit caused by closing isn't a failure of the subprocess
+			select {
+			case <-ctx.Closing():
+				return nil
+			default:
+				return err
+			}
+		},
+	})
+}
+
+// stopCmd sends SIGTERM (or where unsupported, kills) the given subprocess, killing it if it doesn't exit within the given grace period.
+func stopCmd(ctx Context, proc *os.Process, grace time.Duration, chExited <-chan struct{}) {
+	if err := proc.Signal(syscall.SIGTERM); err != nil {
+		proc.Kill()
+		return
+	}
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	select {
+	case <-chExited:
+	case <-timer.C:
+		ctx.Warnf("subprocess %d did not exit within %v of SIGTERM, killing", proc.Pid, grace)
+		proc.Kill()
+	}
+}
+
+// logWriter is an io.Writer that logs each line written to it.
+type logWriter struct {
+	log func(line string)
+	buf []byte
+}
+
+func (w *logWriter) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.log(string(bytes.TrimRight(w.buf[:i], "\r")))
+		w.buf = w.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// flush logs any remaining partial line.
+func (w *logWriter) flush() {
+	if w != nil && len(w.buf) > 0 {
+		w.log(string(w.buf))
+		w.buf = nil
+	}
+}
@@ -0,0 +1,109 @@
+// Package debug serves the live state of a process.Context tree over HTTP.
+package debug
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arcspace/go-cedar/process"
+)
+
+// Handler returns an http.Handler that serves a snapshot of the given root Context and all its descendants.
+//
+// JSON is served if the request has the query param "format=json" or prefers "application/json"; otherwise HTML is served.
+// For example, to mount alongside pprof:
+//
+//	http.Handle("/debug/process", debug.Handler(root))
+//
+// If the query param "id" is given, the registered descendant having that ContextID is served instead (see Task.RegisterIDs and
+// process.LookupID), and a POST with the additional param "action=close" closes that Context, e.g.:
+//
+//	curl -X POST 'localhost:8080/debug/process?id=48231&action=close'
+func Handler(root process.Context) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := root
+		if idStr := r.URL.Query().Get("id"); idStr != "" {
+			id, err := strconv.ParseInt(idStr, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid id", http.StatusBadRequest)
+				return
+			}
+			if target = process.LookupID(id); target == nil || !process.IsDescendant(target, root) {
+				http.Error(w, "no registered context with id "+idStr, http.StatusNotFound)
+				return
+			}
+		}
+
+		switch action := r.URL.Query().Get("action"); {
+		case action == "":
+		case r.Method != http.MethodPost:
+			http.Error(w, "actions require POST", http.StatusMethodNotAllowed)
+			return
+		case action == "close":
+			target.Close()
+		default:
+			http.Error(w, "unknown action "+action, http.StatusBadRequest)
+			return
+		}
+
+		node := process.TreeSnapshot(target)
+
+		if wantsJSON(r) {
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(node)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := pageTemplate.Execute(w, &page{
+			Now:  time.Now(),
+			Root: node,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func wantsJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "json" {
+		return true
+	}
+	return strings.HasPrefix(r.Header.Get("Accept"), "application/json")
+}
+
+type page struct {
+	Now  time.Time
+	Root process.ContextNode
+}
+
+type nodeArgs struct {
+	Now  time.Time
+	Node process.ContextNode
+}
+
+var pageTemplate = template.Must(template.New("page").Funcs(template.FuncMap{
+	"args": func(now time.Time, node process.ContextNode) nodeArgs {
+		return nodeArgs{now, node}
+	},
+	"uptime": func(now, start time.Time) time.Duration {
+		return now.Sub(start).Truncate(time.Millisecond)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><title>process tree</title></head>
+<body>
+<p>Snapshot taken {{.Now.Format "2006-01-02 15:04:05.000"}} &mdash; <a href="?format=json">json</a></p>
+<ul>{{template "node" (args .Now .Root)}}</ul>
+</body>
+</html>
+{{define "node"}}<li><b>{{.Node.Label}}</b> #{{.Node.ID}} &middot; {{.Node.State}} &middot; up {{uptime .Now .Node.StartTime}} &middot; {{.Node.ChildCount}} children
+{{- if .Node.IdleClose}} &middot; idle close {{.Node.IdleClose}}{{if .Node.IdleCloseArmed}} (armed){{end}}{{end}}
+{{- if .Node.Children}}<ul>{{range .Node.Children}}{{template "node" (args $.Now .)}}{{end}}</ul>{{end}}</li>
+{{end}}`))
@@ -0,0 +1,125 @@
+package debug_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arcspace/go-cedar/process"
+	"github.com/arcspace/go-cedar/process/debug"
+)
+
+func serve(t *testing.T, handler http.Handler, method, target string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeNode(t *testing.T, rec *httptest.ResponseRecorder) process.ContextNode {
+	t.Helper()
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var node process.ContextNode
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &node))
+	return node
+}
+
+func TestHandlerTree(t *testing.T) {
+	root, _ := process.Start(&process.Task{Label: "root", RegisterIDs: true})
+	defer root.Close()
+	server, _ := root.StartChild(&process.Task{Label: "server", IdleClose: time.Minute})
+	server.StartChild(&process.Task{Label: "session"})
+	handler := debug.Handler(root)
+
+	node := decodeNode(t, serve(t, handler, http.MethodGet, "/debug/process?format=json", nil))
+	require.Equal(t, root.ContextID(), node.ID)
+	require.Equal(t, "root", node.Label)
+	require.Equal(t, "running", node.State)
+	require.Len(t, node.Children, 1)
+	require.Equal(t, "server", node.Children[0].Label)
+	require.Equal(t, time.Minute, node.Children[0].IdleClose)
+	require.Len(t, node.Children[0].Children, 1)
+	require.Equal(t, "session", node.Children[0].Children[0].Label)
+
+	// Accept: application/json is equivalent to format=json
+	node = decodeNode(t, serve(t, handler, http.MethodGet, "/debug/process", http.Header{"Accept": {"application/json"}}))
+	require.Equal(t, "root", node.Label)
+
+	rec := serve(t, handler, http.MethodGet, "/debug/process", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html"))
+	body := rec.Body.String()
+	for _, label := range []string{"<b>root</b>", "<b>server</b>", "<b>session</b>", "idle close 1m0s"} {
+		require.Contains(t, body, label)
+	}
+
+	// A registered descendant can be served by itself
+	id := strconv.FormatInt(server.ContextID(), 10)
+	node = decodeNode(t, serve(t, handler, http.MethodGet, "/debug/process?format=json&id="+id, nil))
+	require.Equal(t, "server", node.Label)
+	require.Len(t, node.Children, 1)
+}
+
+func TestHandlerClose(t *testing.T) {
+	root, _ := process.Start(&process.Task{Label: "root", RegisterIDs: true})
+	defer root.Close()
+	session, _ := root.StartChild(&process.Task{Label: "session"})
+	handler := debug.Handler(root)
+	target := "/debug/process?format=json&action=close&id=" + strconv.FormatInt(session.ContextID(), 10)
+
+	// Actions require POST
+	rec := serve(t, handler, http.MethodGet, target, nil)
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	require.Equal(t, process.Running, session.State())
+
+	rec = serve(t, handler, http.MethodPost, strings.Replace(target, "action=close", "action=pause", 1), nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, process.Running, session.State())
+
+	node := decodeNode(t, serve(t, handler, http.MethodPost, target, nil))
+	require.Equal(t, "session", node.Label)
+	select {
+	case <-session.Done():
+	case <-time.After(time.Second):
+		t.Fatal("session was not closed")
+	}
+	require.Equal(t, process.CloseKind_Cancelled, session.CloseKind())
+	require.Equal(t, process.Running, root.State())
+}
+
+func TestHandlerBadID(t *testing.T) {
+	root, _ := process.Start(&process.Task{Label: "root", RegisterIDs: true})
+	defer root.Close()
+	other, _ := process.Start(&process.Task{Label: "other", RegisterIDs: true})
+	defer other.Close()
+	closed, _ := root.StartChild(&process.Task{Label: "closed"})
+	closed.Close()
+	<-closed.Done()
+	handler := debug.Handler(root)
+
+	for _, tc := range []struct {
+		id     string
+		status int
+	}{
+		{"abc", http.StatusBadRequest},
+		{"12.5", http.StatusBadRequest},
+		{"999999999", http.StatusNotFound},
+		{strconv.FormatInt(closed.ContextID(), 10), http.StatusNotFound},
+		{strconv.FormatInt(other.ContextID(), 10), http.StatusNotFound}, // registered, but not within root
+	} {
+		rec := serve(t, handler, http.MethodPost, "/debug/process?action=close&id="+tc.id, nil)
+		require.Equal(t, tc.status, rec.Code, tc.id)
+	}
+	require.Equal(t, process.Running, other.State())
+}
@@ -0,0 +1,173 @@
+package process
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventKind identifies a lifecycle event published to subscribers (see Subscribe).
+// Kinds are bit flags so that an EventFilter can select several at once.
+type EventKind uint32
+
+const (
+	Event_Started        EventKind = 1 << iota // A Context was started (see Observer.OnContextStarted)
+	Event_Closing                              // A Context began closing (see Observer.OnContextClosing)
+	Event_Closed                               // A Context closed (see Observer.OnContextDone); Event.Err is its Err() cause
+	Event_Panicked                             // A Context's OnStart or OnRun panicked; Event.Err is the *PanicError
+	Event_IdleCloseArmed                       // CloseWhenIdle() was first called on a Context
+
+	Event_All = Event_Started | Event_Closing | Event_Closed | Event_Panicked | Event_IdleCloseArmed
+)
+
+func (kind EventKind) String() string {
+	switch kind {
+	case Event_Started:
+		return "started"
+	case Event_Closing:
+		return "closing"
+	case Event_Closed:
+		return "closed"
+	case Event_Panicked:
+		return "panicked"
+	case Event_IdleCloseArmed:
+		return "idle-close-armed"
+	}
+	return "unknown"
+}
+
+// Event is a lifecycle event of a Context.
+type Event struct {
+	Kind    EventKind
+	Context Context
+	Time    time.Time
+	Err     error
+}
+
+// EventFilter specifies which events a subscriber receives.
+type EventFilter struct {
+	Kinds   EventKind // Event kinds to receive (or all kinds if 0)
+	Root    Context   // If set, only events for Root and its descendants are received
+	BufSize int       // Size of the returned channel's buffer (or 256 if 0)
+}
+
+// DefaultEventBufSize is the buffer size used for Subscribe() channels when EventFilter.BufSize is 0.
+const DefaultEventBufSize = 256
+
+type subscriber struct {
+	filter EventFilter
+	root   *ctx
+	events chan Event
+}
+
+var gEvents struct {
+	mu     sync.RWMutex
+	subs   atomic.Value // []*subscriber, replaced on each change
+	remove func()       // removes eventObserver once there are no subscribers
+}
+
+// Subscribe returns a channel of lifecycle events of all Contexts (as selected by the given filter) from here forward.
+// Events are delivered without blocking, so events are dropped for a subscriber whose channel buffer is full.
+// Call Unsubscribe() when done, which closes the returned channel.
+func Subscribe(filter EventFilter) <-chan Event {
+	if filter.Kinds == 0 {
+		filter.Kinds = Event_All
+	}
+	if filter.BufSize <= 0 {
+		filter.BufSize = DefaultEventBufSize
+	}
+	sub := &subscriber{
+		filter: filter,
+		events: make(chan Event, filter.BufSize),
+	}
+	sub.root, _ = asCtx(filter.Root)
+
+	gEvents.mu.Lock()
+	defer gEvents.mu.Unlock()
+
+	prev, _ := gEvents.subs.Load().([]*subscriber)
+	gEvents.subs.Store(append(append([]*subscriber(nil), prev...), sub))
+	if gEvents.remove == nil {
+		gEvents.remove = AddObserver(eventObserver{})
+	}
+	return sub.events
+}
+
+// Unsubscribe stops and closes the given channel returned by Subscribe().
+func Unsubscribe(events <-chan Event) {
+	gEvents.mu.Lock()
+	defer gEvents.mu.Unlock()
+
+	prev, _ := gEvents.subs.Load().([]*subscriber)
+	subs := make([]*subscriber, 0, len(prev))
+	for _, sub := range prev {
+		if (<-chan Event)(sub.events) == events {
+			close(sub.events)
+		} else {
+			subs = append(subs, sub)
+		}
+	}
+	gEvents.subs.Store(subs)
+
+	if len(subs) == 0 && gEvents.remove != nil {
+		gEvents.remove()
+		gEvents.remove = nil
+	}
+}
+
+// publishEvent delivers the given event to all interested subscribers.
+func publishEvent(kind EventKind, p *ctx, err error) {
+	if subs, _ := gEvents.subs.Load().([]*subscriber); len(subs) == 0 {
+		return
+	}
+
+	// Hold mu so that Unsubscribe() can't close a channel while it is being sent to
+	gEvents.mu.RLock()
+	defer gEvents.mu.RUnlock()
+	subs, _ := gEvents.subs.Load().([]*subscriber)
+
+	ev := Event{
+		Kind:    kind,
+		Context: p,
+		Time:    p.clock.Now(), // per the emitting Context's Clock, consistent with its Stats and History
+		Err:     err,
+	}
+	for _, sub := range subs {
+		if sub.filter.Kinds&kind == 0 || !sub.accepts(p) {
+			continue
+		}
+		select {
+		case sub.events <- ev:
+		default:
+		}
+	}
+}
+
+// accepts returns true if the given Context is within this subscriber's Root.
+func (sub *subscriber) accepts(p *ctx) bool {
+	if sub.filter.Root == nil {
+		return true
+	}
+	for ci := p; ci != nil; ci = ci.getParent() {
+		if ci == sub.root {
+			return true
+		}
+	}
+	return false
+}
+
+// eventObserver publishes Observer notifications as events while there are subscribers.
+type eventObserver struct{}
+
+func (eventObserver) OnContextStarted(ci Context) {
+	publishEvent(Event_Started, ci.(*ctx), nil)
+}
+
+func (eventObserver) OnContextClosing(ci Context) {
+	publishEvent(Event_Closing, ci.(*ctx), nil)
+}
+
+func (eventObserver) OnContextDone(ci Context) {
+	p := ci.(*ctx)
+	publishEvent(Event_Closed, p, p.err)
+}
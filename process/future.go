@@ -0,0 +1,74 @@
+package process
+
+import (
+	"errors"
+	"time"
+)
+
+// Future is the eventual result of a function started via GoValue().
+type Future[T any] struct {
+	ctx   Context
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// GoValue is like Go() but fn returns a value (or error) that is made available via the returned Future.
+// If the child Context can't be started, the Future is immediately done with the error from StartChild().
+// If fn panics, the Future's error and the child's Cause() are the resulting *PanicError, and the child closes with CloseKind_Failed.
+func GoValue[T any](parent Context, label string, fn func(ctx Context) (T, error)) *Future[T] {
+	f := &Future[T]{
+		done: make(chan struct{}),
+	}
+
+	var err error
+	f.ctx, err = parent.StartChild(&Task{
+		Label:     label,
+		IdleClose: time.Nanosecond,
+		OnRun: func(child Context) {
+			defer close(f.done)
+			f.err = child.(*ctx).callRecover(func() error {
+				var err error
+				f.value, err = fn(child)
+				return err
+			})
+
+			// As with OnRun, a panic fails the child
+			var panicErr *PanicError
+			if errors.As(f.err, &panicErr) {
+				child.(*ctx).closeAs(CloseKind_Failed, f.err)
+			}
+		},
+	})
+	if err != nil {
+		f.err = err
+		close(f.done)
+	}
+	return f
+}
+
+// Context returns the child Context running fn (or nil if it could not be started).
+func (f *Future[T]) Context() Context {
+	return f.ctx
+}
+
+// Done returns a channel that is closed once fn has returned.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until fn has returned and then returns its results.
+func (f *Future[T]) Wait() (T, error) {
+	<-f.done
+	return f.value, f.err
+}
+
+// Err returns the error returned by fn, or nil if fn has not yet returned.
+func (f *Future[T]) Err() error {
+	select {
+	case <-f.done:
+		return f.err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,130 @@
+// Package grpcserver runs a *grpc.Server as a process.Context, optionally publishing health via the standard gRPC health-checking service.
+package grpcserver
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/arcspace/go-cedar/process"
+)
+
+// DefaultStopGrace is how long GracefulStop() is given before falling back to Stop().
+var DefaultStopGrace = 10 * time.Second
+
+// DefaultHealthInterval is how often the health service is updated when Opts.HealthInterval is 0.
+var DefaultHealthInterval = 5 * time.Second
+
+// Opts specifies how Start() runs a *grpc.Server.
+type Opts struct {
+	Addr      string        // TCP address to listen on if Listener is nil
+	Listener  net.Listener  // If set, the server serves on this listener
+	StopGrace time.Duration // Time allowed for GracefulStop() before Stop() is called (DefaultStopGrace if 0)
+
+	// If set, the serving status of each of HealthServices (or if empty, the overall "" service) on this health server
+	// tracks the parent Context's Health(): SERVING unless Unhealthy, and NOT_SERVING once closing.
+	// Health is expected to already be registered on the grpc.Server (see healthpb.RegisterHealthServer).
+	Health         *health.Server
+	HealthServices []string
+	HealthInterval time.Duration // How often Health is updated (DefaultHealthInterval if 0)
+}
+
+// Start starts a child Context of parent that serves srv until closed.
+//
+// The listener is opened during StartChild(), so an error listening on opts.Addr is returned immediately.
+// When the child closes, health is set to NOT_SERVING and then srv.GracefulStop() is called, followed by srv.Stop()
+// if in-flight RPCs don't complete within opts.StopGrace.  If srv.Serve() fails, the child closes with that error as its Cause().
+func Start(parent process.Context, label string, srv *grpc.Server, opts Opts) (process.Context, error) {
+	if opts.StopGrace <= 0 {
+		opts.StopGrace = DefaultStopGrace
+	}
+	if opts.HealthInterval <= 0 {
+		opts.HealthInterval = DefaultHealthInterval
+	}
+	services := opts.HealthServices
+	if len(services) == 0 {
+		services = []string{""}
+	}
+	var healthMu sync.Mutex // orders periodic updates with the NOT_SERVING set once closing
+	setHealth := func(status healthpb.HealthCheckResponse_ServingStatus) {
+		if opts.Health != nil {
+			for _, svc := range services {
+				opts.Health.SetServingStatus(svc, status)
+			}
+		}
+	}
+
+	ln := opts.Listener
+	return parent.StartChild(&process.Task{
+		Label: label,
+		OnStart: func(ctx process.Context) error {
+			if ln == nil {
+				var err error
+				if ln, err = net.Listen("tcp", opts.Addr); err != nil {
+					return err
+				}
+			}
+			if opts.Health != nil {
+				server := ctx
+				updateHealth := func(ctx process.Context) {
+					healthMu.Lock()
+					defer healthMu.Unlock()
+
+					// Once closing, stay NOT_SERVING while in-flight RPCs drain
+					select {
+					case <-server.Closing():
+						return
+					default:
+					}
+					if parent.Health() == process.Unhealthy {
+						setHealth(healthpb.HealthCheckResponse_NOT_SERVING)
+					} else {
+						setHealth(healthpb.HealthCheckResponse_SERVING)
+					}
+				}
+				updateHealth(ctx)
+				if _, err := ctx.GoPeriodic("health", opts.HealthInterval, updateHealth); err != nil {
+					// Serve() won't be called to take ownership of a listener opened here, so close it
+					if opts.Listener == nil {
+						ln.Close()
+						ln = nil
+					}
+					return err
+				}
+			}
+			ctx.Infof(1, "serving gRPC on %v", ln.Addr())
+			return nil
+		},
+		OnRunErr: func(ctx process.Context) error {
+			err := srv.Serve(ln)
+			if err == grpc.ErrServerStopped {
+				return nil
+			}
+			return err
+		},
+		OnClosing: func() {
+			healthMu.Lock()
+			setHealth(healthpb.HealthCheckResponse_NOT_SERVING)
+			healthMu.Unlock()
+
+			stopped := make(chan struct{})
+			go func() {
+				srv.GracefulStop()
+				close(stopped)
+			}()
+
+			timer := time.NewTimer(opts.StopGrace)
+			defer timer.Stop()
+			select {
+			case <-stopped:
+			case <-timer.C:
+				srv.Stop()
+				<-stopped
+			}
+		},
+	})
+}
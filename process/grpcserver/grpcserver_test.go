@@ -0,0 +1,136 @@
+package grpcserver_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/arcspace/go-cedar/process"
+	"github.com/arcspace/go-cedar/process/grpcserver"
+)
+
+func TestStart(t *testing.T) {
+	root, _ := process.Start(&process.Task{Label: "root"})
+	defer root.Close()
+
+	srv := grpc.NewServer()
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server, err := grpcserver.Start(root, "grpc", srv, grpcserver.Opts{
+		Listener: ln,
+		Health:   hs,
+	})
+	require.NoError(t, err)
+
+	conn, err := grpc.Dial(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	server.Close()
+	<-server.Done()
+	require.Equal(t, process.CloseKind_Cancelled, server.CloseKind())
+
+	resp, err = hs.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+}
+
+func TestNotServingWhileDraining(t *testing.T) {
+	root, _ := process.Start(&process.Task{Label: "root"})
+	defer root.Close()
+
+	srv := grpc.NewServer()
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server, err := grpcserver.Start(root, "grpc", srv, grpcserver.Opts{
+		Listener:       ln,
+		Health:         hs,
+		HealthInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	conn, err := grpc.Dial(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// An open Watch stream is an in-flight RPC, so GracefulStop() blocks until it ends
+	watchCtx, cancelWatch := context.WithCancel(context.Background())
+	defer cancelWatch()
+	watch, err := healthpb.NewHealthClient(conn).Watch(watchCtx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	resp, err := watch.Recv()
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	server.Close()
+	for i := 0; i < 20; i++ {
+		time.Sleep(5 * time.Millisecond)
+		resp, err := hs.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+		require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+	}
+
+	cancelWatch()
+	<-server.Done()
+}
+
+// closingParent is a Context whose Health() closes the named child, so that a child's OnStart fails partway through.
+type closingParent struct {
+	process.Context
+	child string
+}
+
+func (p *closingParent) Health() process.HealthStatus {
+	if child := p.GetChild(p.child); child != nil {
+		child.Close()
+	}
+	return p.Context.Health()
+}
+
+func TestStartFails(t *testing.T) {
+	root, _ := process.Start(&process.Task{Label: "root"})
+	defer root.Close()
+
+	// A listener opened by Start() is closed if starting fails
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+	_, err = grpcserver.Start(&closingParent{root, "opened"}, "opened", grpc.NewServer(), grpcserver.Opts{
+		Addr:   addr,
+		Health: health.NewServer(),
+	})
+	require.ErrorIs(t, err, process.ErrClosing)
+	ln, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+
+	// ... but a listener passed in is left to the caller
+	defer ln.Close()
+	_, err = grpcserver.Start(&closingParent{root, "given"}, "given", grpc.NewServer(), grpcserver.Opts{
+		Listener: ln,
+		Health:   health.NewServer(),
+	})
+	require.ErrorIs(t, err, process.ErrClosing)
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	conn.Close()
+}
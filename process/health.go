@@ -0,0 +1,206 @@
+package process
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HealthStatus is the aggregated health of a Context and its descendants.
+type HealthStatus int32
+
+const (
+	Healthy   HealthStatus = iota // This Context and all its descendants are healthy.
+	Degraded                      // One or more non-critical descendants are unhealthy, or a descendant is restarting or flapping (see FlapThreshold).
+	Unhealthy                     // This Context is unhealthy or a Task.Critical child is unhealthy or down.
+)
+
+func (s HealthStatus) String() string {
+	switch s {
+	case Healthy:
+		return "healthy"
+	case Degraded:
+		return "degraded"
+	case Unhealthy:
+		return "unhealthy"
+	}
+	return "unknown"
+}
+
+func (p *ctx) SetHealthy(healthy bool) {
+	var unhealthy int32
+	if !healthy {
+		unhealthy = 1
+	}
+	atomic.StoreInt32(&p.unhealthy, unhealthy)
+}
+
+func (p *ctx) Health() HealthStatus {
+	status := p.ownHealth(nil)
+	if status == Unhealthy {
+		return status
+	}
+	running := atomic.LoadInt32(&p.state) == Running
+
+	var subBuf [20]Context
+	for _, ci := range p.GetChildren(subBuf[:0]) {
+		child := ci.(*ctx)
+		if status = foldChildHealth(status, child, child.Health(), running); status == Unhealthy {
+			break
+		}
+	}
+	return status
+}
+
+// ownHealth returns the health of this Context apart from its children, given the result of its Task.HealthCheck (if run).
+func (p *ctx) ownHealth(checkErr error) HealthStatus {
+	if atomic.LoadInt32(&p.unhealthy) != 0 || checkErr != nil || atomic.LoadInt32(&p.criticalDown) > 0 {
+		return Unhealthy
+	}
+	if atomic.LoadInt32(&p.restarting) > 0 {
+		return Degraded
+	}
+	return Healthy
+}
+
+// foldChildHealth returns the given status of a parent after accounting for the given child's health.
+func foldChildHealth(status HealthStatus, child *ctx, childHealth HealthStatus, parentRunning bool) HealthStatus {
+	critical := child.task.Critical
+
+	// A critical child that is closing out from under a running parent is considered down
+	if critical && parentRunning && atomic.LoadInt32(&child.state) != Running {
+		childHealth = Unhealthy
+	}
+
+	// A child restarting in a tight loop isn't healthy even while it happens to be running
+	if childHealth == Healthy && child.restart != nil && child.restart.flapping(child.clock.Now()) {
+		childHealth = Degraded
+	}
+
+	switch {
+	case status == Unhealthy:
+	case childHealth == Unhealthy && critical:
+		status = Unhealthy
+	case childHealth != Healthy:
+		status = Degraded
+	}
+	return status
+}
+
+func (s HealthStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *HealthStatus) UnmarshalText(text []byte) error {
+	for _, status := range []HealthStatus{Healthy, Degraded, Unhealthy} {
+		if string(text) == status.String() {
+			*s = status
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown health status %q", text)
+}
+
+// HealthReport is the health of a Context and its descendants (see Context.HealthReport).
+type HealthReport struct {
+	ID       int64          `json:"id"`
+	Label    string         `json:"label"`
+	Status   HealthStatus   `json:"status"`            // Aggregated as with Context.Health(), where a failed HealthCheck makes a Context Unhealthy
+	Err      string         `json:"error,omitempty"`   // Error returned by Task.HealthCheck
+	Latency  time.Duration  `json:"latency,omitempty"` // How long Task.HealthCheck took
+	Children []HealthReport `json:"children,omitempty"`
+}
+
+// healthNode is used by HealthReport() to run all HealthChecks concurrently before aggregating.
+type healthNode struct {
+	ctx      *ctx
+	checkErr error
+	latency  time.Duration
+	children []*healthNode
+}
+
+func (p *ctx) HealthReport() HealthReport {
+	var wg sync.WaitGroup
+	root := p.healthTree(&wg)
+	wg.Wait()
+	return root.report()
+}
+
+// healthTree snapshots this Context's subtree, starting each HealthCheck in its own goroutine.
+func (p *ctx) healthTree(wg *sync.WaitGroup) *healthNode {
+	node := &healthNode{ctx: p}
+	if check := p.task.HealthCheck; check != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := p.clock.Now()
+			node.checkErr = p.callRecover(func() error {
+				return check(p)
+			})
+			node.latency = p.clock.Now().Sub(start)
+		}()
+	}
+
+	var subBuf [20]Context
+	for _, ci := range p.GetChildren(subBuf[:0]) {
+		node.children = append(node.children, ci.(*ctx).healthTree(wg))
+	}
+	return node
+}
+
+func (node *healthNode) report() HealthReport {
+	p := node.ctx
+	report := HealthReport{
+		ID:      p.id,
+		Label:   p.Label(),
+		Status:  p.ownHealth(node.checkErr),
+		Latency: node.latency,
+	}
+	if node.checkErr != nil {
+		report.Err = node.checkErr.Error()
+	}
+
+	running := atomic.LoadInt32(&p.state) == Running
+	for _, child := range node.children {
+		childReport := child.report()
+		report.Status = foldChildHealth(report.Status, child.ctx, childReport.Status, running)
+		report.Children = append(report.Children, childReport)
+	}
+	return report
+}
+
+// HealthHandler returns an http.Handler (e.g. for /healthz) that responds with root's HealthReport as JSON,
+// with status 503 if root is Unhealthy (and 200 otherwise).
+func HealthHandler(root Context) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := root.HealthReport()
+		writeHealth(w, report, report.Status != Unhealthy)
+	})
+}
+
+// ReadyHandler returns an http.Handler (e.g. for /readyz) that is like HealthHandler() except that it also responds
+// with status 503 once root is closing or draining, so that load balancers stop routing to it.
+func ReadyHandler(root Context) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := root.HealthReport()
+		ready := report.Status != Unhealthy && root.State() == Running
+		if p, ok := asCtx(root); ok && p.isDraining() {
+			ready = false
+		}
+		writeHealth(w, report, ready)
+	})
+}
+
+func writeHealth(w http.ResponseWriter, report HealthReport, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+}
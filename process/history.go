@@ -0,0 +1,80 @@
+package process
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// HistoryEntry is a lifecycle event or log entry retained by a Context (see Task.HistorySize).
+type HistoryEntry struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"` // Lifecycle event (e.g. "started", "child closed") or log severity (e.g. "warning")
+	Msg   string    `json:"msg,omitempty"`
+}
+
+func (entry HistoryEntry) String() string {
+	if entry.Msg == "" {
+		return fmt.Sprintf("%s %s", entry.Time.Format("15:04:05.000"), entry.Event)
+	}
+	return fmt.Sprintf("%s %s: %s", entry.Time.Format("15:04:05.000"), entry.Event, entry.Msg)
+}
+
+// history is a fixed-size ring of HistoryEntry.
+type history struct {
+	mu      sync.Mutex
+	entries []HistoryEntry
+	next    int  // index of the next entry to write
+	full    bool // set once entries has wrapped
+}
+
+func newHistory(size int) *history {
+	return &history{
+		entries: make([]HistoryEntry, size),
+	}
+}
+
+// record adds an entry to this Context's history (if retained).
+func (p *ctx) record(event, msg string) {
+	h := p.history
+	if h == nil {
+		return
+	}
+	entry := HistoryEntry{
+		Time:  p.clock.Now(),
+		Event: event,
+		Msg:   msg,
+	}
+
+	h.mu.Lock()
+	h.entries[h.next] = entry
+	if h.next++; h.next == len(h.entries) {
+		h.next = 0
+		h.full = true
+	}
+	h.mu.Unlock()
+}
+
+func (p *ctx) History() []HistoryEntry {
+	h := p.history
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.full {
+		return append([]HistoryEntry(nil), h.entries[:h.next]...)
+	}
+	entries := make([]HistoryEntry, 0, len(h.entries))
+	entries = append(entries, h.entries[h.next:]...)
+	return append(entries, h.entries[:h.next]...)
+}
+
+// errMsg returns err's message, or "" if err is nil.
+func errMsg(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
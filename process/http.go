@@ -0,0 +1,70 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// DefaultHTTPShutdownGrace is how long HTTPServer() allows in-flight requests to finish once closing.
+var DefaultHTTPShutdownGrace = 10 * time.Second
+
+// HTTPServerOpts specifies how HTTPServer() runs an http.Server.
+type HTTPServerOpts struct {
+	ShutdownGrace time.Duration // Time allowed for srv.Shutdown() before remaining connections are closed (DefaultHTTPShutdownGrace if 0)
+	Listener      net.Listener  // If set, srv serves on this listener rather than listening on srv.Addr
+}
+
+// HTTPServer starts a child Context of parent that serves srv until closed, where opts may be nil.
+//
+// The listener is opened during StartChild(), so an error binding srv.Addr is returned immediately.
+// When the child closes, srv.Shutdown() is called, allowing in-flight requests up to opts.ShutdownGrace to complete.
+// If srv.Serve() fails, the child is closed with that error as its Cause().
+// If srv.BaseContext is nil, requests' contexts derive from the child, so their Value() lookups see the process tree.
+func HTTPServer(parent Context, label string, srv *http.Server, opts *HTTPServerOpts) (Context, error) {
+	var o HTTPServerOpts
+	if opts != nil {
+		o = *opts
+	}
+	if o.ShutdownGrace <= 0 {
+		o.ShutdownGrace = DefaultHTTPShutdownGrace
+	}
+
+	ln := o.Listener
+	return parent.StartChild(&Task{
+		Label: label,
+		OnStart: func(ctx Context) error {
+			if ln == nil {
+				addr := srv.Addr
+				if addr == "" {
+					addr = ":http"
+				}
+				var err error
+				if ln, err = net.Listen("tcp", addr); err != nil {
+					return err
+				}
+			}
+			if srv.BaseContext == nil {
+				srv.BaseContext = func(net.Listener) context.Context { return ctx }
+			}
+			ctx.Infof(1, "serving HTTP on %v", ln.Addr())
+			return nil
+		},
+		OnRunErr: func(ctx Context) error {
+			err := srv.Serve(ln)
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		},
+		OnClosing: func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), o.ShutdownGrace)
+			defer cancel()
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				srv.Close()
+			}
+		},
+	})
+}
@@ -0,0 +1,84 @@
+package process
+
+import (
+	"sync/atomic"
+)
+
+// JobPool runs submitted jobs on a bounded set of long-lived worker Contexts, allowing large numbers of short jobs to run
+// without each one incurring the cost of its own Context (and goroutine, ID, and parent bookkeeping).
+//
+// Workers are started as needed (up to maxWorkers) and remain running until the JobPool is closed.
+type JobPool struct {
+	Context
+	jobs       chan func(ctx Context)
+	maxWorkers int32
+	numWorkers int32
+}
+
+// StartNewJobPool starts a JobPool as a child of the given parent, which closes when the parent closes.
+// If maxWorkers < 1, then 1 is used.
+func StartNewJobPool(parent Context, label string, maxWorkers int) (*JobPool, error) {
+	if maxWorkers < 1 {
+		maxWorkers = 1
+	}
+	p := &JobPool{
+		jobs:       make(chan func(ctx Context)),
+		maxWorkers: int32(maxWorkers),
+	}
+
+	var err error
+	p.Context, err = parent.StartChild(&Task{
+		Label: label,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Submit passes fn to an idle worker, starting a new worker if none are idle and the pool is below its max.
+// It blocks while all workers are busy, returning ErrClosed if this JobPool starts closing before fn is accepted.
+//
+// fn is passed the worker's Context, which is shared by all jobs run by that worker.
+// Jobs should return promptly once ctx.Closing() fires.
+func (p *JobPool) Submit(fn func(ctx Context)) error {
+	select {
+	case p.jobs <- fn:
+		return nil
+	default:
+	}
+
+	if atomic.AddInt32(&p.numWorkers, 1) <= p.maxWorkers {
+		if _, err := p.Go("worker", p.work); err != nil {
+			atomic.AddInt32(&p.numWorkers, -1)
+			return ErrClosed
+		}
+	} else {
+		atomic.AddInt32(&p.numWorkers, -1)
+	}
+
+	select {
+	case p.jobs <- fn:
+		return nil
+	case <-p.Closing():
+		return ErrClosed
+	}
+}
+
+// NumWorkers returns the number of workers currently running.
+func (p *JobPool) NumWorkers() int {
+	return int(atomic.LoadInt32(&p.numWorkers))
+}
+
+func (p *JobPool) work(ctx Context) {
+	defer atomic.AddInt32(&p.numWorkers, -1)
+
+	for {
+		select {
+		case fn := <-p.jobs:
+			fn(ctx)
+		case <-ctx.Closing():
+			return
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package process
+
+// Join returns a channel that is closed once all the given Contexts are Done (nil entries are ignored).
+// If all are already Done (or none are given), the returned channel is already closed.
+func Join(ctxs ...Context) <-chan struct{} {
+	pending := make([]Context, 0, len(ctxs))
+	for _, ci := range ctxs {
+		if ci != nil && !isDoneCtx(ci) {
+			pending = append(pending, ci)
+		}
+	}
+	if len(pending) == 0 {
+		return gClosedChan
+	}
+
+	chDone := make(chan struct{})
+	go func() {
+		for _, ci := range pending {
+			<-ci.Done()
+		}
+		close(chDone)
+	}()
+	return chDone
+}
+
+func isDoneCtx(ci Context) bool {
+	select {
+	case <-ci.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *ctx) WaitChildren(labels ...string) {
+	var children []Context
+	p.subsMu.Lock()
+	for child := p.firstChild; child != nil; child = child.nextSib {
+		if len(labels) == 0 || hasLabel(labels, child.Label()) {
+			children = append(children, child)
+		}
+	}
+	p.subsMu.Unlock()
+
+	<-Join(children...)
+}
+
+func hasLabel(labels []string, label string) bool {
+	for _, li := range labels {
+		if li == label {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package process
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// latch is a one-shot signal whose channel is only allocated if something actually waits on it.
+// Since most Contexts are never selected on before they close, this saves a channel allocation per signal.
+type latch struct {
+	mu        sync.Mutex
+	ch        atomic.Value // chan struct{}, set on first wait()
+	fired     int32
+	callbacks []func() // see onFire(), where canceled entries are nil
+}
+
+// gClosedChan is handed out by latches that have already fired.
+var gClosedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// wait returns a channel that is closed once fire() is called.
+func (l *latch) wait() <-chan struct{} {
+	if ch, ok := l.ch.Load().(chan struct{}); ok {
+		return ch
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ch, ok := l.ch.Load().(chan struct{})
+	if !ok {
+		if atomic.LoadInt32(&l.fired) != 0 {
+			ch = gClosedChan
+		} else {
+			ch = make(chan struct{})
+		}
+		l.ch.Store(ch)
+	}
+	return ch
+}
+
+// fire releases all current and future waiters and must be called at most once.
+// Writes made before fire() are visible to any goroutine that observes isFired() or a closed wait() channel.
+func (l *latch) fire() {
+	l.mu.Lock()
+	atomic.StoreInt32(&l.fired, 1)
+	if ch, ok := l.ch.Load().(chan struct{}); ok {
+		close(ch)
+	}
+	l.mu.Unlock()
+}
+
+// runCallbacks calls the callbacks registered via onFire() and must be called (once) after fire().
+// This is separate from fire() so that callbacks are never called while the caller of fire() holds locks.
+func (l *latch) runCallbacks() {
+	l.mu.Lock()
+	callbacks := l.callbacks
+	l.callbacks = nil
+	l.mu.Unlock()
+
+	for _, fn := range callbacks {
+		if fn != nil {
+			fn()
+		}
+	}
+}
+
+// onFire arranges for fn to be called by runCallbacks() (or calls it immediately if already fired).
+// The returned cancel func prevents fn from being called if it has not been already.
+func (l *latch) onFire(fn func()) (cancel func()) {
+	l.mu.Lock()
+	if atomic.LoadInt32(&l.fired) != 0 {
+		l.mu.Unlock()
+		fn()
+		return func() {}
+	}
+	idx := len(l.callbacks)
+	l.callbacks = append(l.callbacks, fn)
+	l.mu.Unlock()
+
+	return func() {
+		l.mu.Lock()
+		if idx < len(l.callbacks) {
+			l.callbacks[idx] = nil
+		}
+		l.mu.Unlock()
+	}
+}
+
+func (l *latch) isFired() bool {
+	return atomic.LoadInt32(&l.fired) != 0
+}
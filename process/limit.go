@@ -0,0 +1,153 @@
+package process
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket rate limiter whose Wait() aborts once the Context it belongs to is closing (see Context.Limiter).
+type Limiter struct {
+	owner  *ctx
+	rate   float64 // tokens per second
+	burst  float64
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// Semaphore limits concurrency, where Acquire() aborts once the Context it belongs to is closing (see Context.Semaphore).
+type Semaphore struct {
+	owner *ctx
+	slots chan struct{}
+}
+
+type limiterKey string
+type semaphoreKey string
+
+func (p *ctx) Limiter(name string, rate float64, burst int) *Limiter {
+	return p.valueOrCreate(limiterKey(name), func() interface{} {
+		if burst < 1 {
+			burst = 1
+		}
+		return &Limiter{
+			owner:  p,
+			rate:   rate,
+			burst:  float64(burst),
+			tokens: float64(burst),
+			last:   p.clock.Now(),
+		}
+	}).(*Limiter)
+}
+
+func (p *ctx) Semaphore(name string, n int) *Semaphore {
+	return p.valueOrCreate(semaphoreKey(name), func() interface{} {
+		if n < 1 {
+			n = 1
+		}
+		return &Semaphore{
+			owner: p,
+			slots: make(chan struct{}, n),
+		}
+	}).(*Semaphore)
+}
+
+// valueOrCreate returns Value(key) if non-nil, else sets key on this Context to the value returned by create().
+func (p *ctx) valueOrCreate(key interface{}, create func() interface{}) interface{} {
+	if val := p.Value(key); val != nil {
+		return val
+	}
+
+	p.valuesMu.Lock()
+	defer p.valuesMu.Unlock()
+	if val := p.values[key]; val != nil {
+		return val
+	}
+	if p.values == nil {
+		p.values = make(map[interface{}]interface{})
+	}
+	val := create()
+	p.values[key] = val
+	return val
+}
+
+// Allow takes a token if one is available, returning false otherwise.
+func (l *Limiter) Allow() bool {
+	if l.rate <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Wait blocks until a token is available and takes it, returning ErrClosing if the Limiter's Context starts closing first.
+func (l *Limiter) Wait() error {
+	if l.rate <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	l.refill()
+	l.tokens-- // reserve a token, going into debt if none are available
+	deficit := -l.tokens
+	l.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+	delay := time.Duration(math.Ceil(deficit / l.rate * float64(time.Second)))
+	if !l.owner.Sleep(delay) {
+		l.mu.Lock()
+		l.tokens++ // return the reservation
+		l.mu.Unlock()
+		return ErrClosing
+	}
+	return nil
+}
+
+// refill adds the tokens accrued since the last refill.
+// l.mu must be held.
+func (l *Limiter) refill() {
+	now := l.owner.clock.Now()
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = math.Min(l.burst, l.tokens+elapsed.Seconds()*l.rate)
+		l.last = now
+	}
+}
+
+// Acquire blocks until a slot is available and takes it, returning ErrClosing if the Semaphore's Context starts closing first.
+// Each successful Acquire() must be followed by a Release().
+func (s *Semaphore) Acquire() error {
+	select {
+	case <-s.owner.Closing():
+		return ErrClosing
+	default:
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-s.owner.Closing():
+		return ErrClosing
+	}
+}
+
+// TryAcquire takes a slot if one is available, returning false otherwise.
+func (s *Semaphore) TryAcquire() bool {
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release returns a slot taken by Acquire() or TryAcquire().
+func (s *Semaphore) Release() {
+	<-s.slots
+}
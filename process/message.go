@@ -0,0 +1,25 @@
+package process
+
+func (p *ctx) Broadcast(msg any) {
+	var buf [16]Context
+	for _, ci := range p.GetChildren(buf[:0]) {
+		Walk(ci, func(ci Context, depth int) bool {
+			child := ci.(*ctx)
+			if child.task.OnMessage != nil && child.State() == Running {
+				child.deliver(msg)
+			}
+			return true
+		})
+	}
+}
+
+// deliver passes msg to this Context's OnMessage, closing this Context if it panics.
+func (p *ctx) deliver(msg any) {
+	err := p.callRecover(func() error {
+		p.task.OnMessage(p, msg)
+		return nil
+	})
+	if err != nil {
+		p.closeAs(CloseKind_Failed, err)
+	}
+}
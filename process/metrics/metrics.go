@@ -0,0 +1,242 @@
+// Package metrics tracks process.Context lifecycle counters and gauges, exporting them in the Prometheus text
+// exposition format and via expvar.
+//
+// Rather than implement a prometheus.Collector (which would make client_golang a dependency of this module),
+// Metrics is an http.Handler serving the text exposition format directly, so it can be scraped as its own target
+// or its output appended to an existing /metrics handler via WritePrometheus().
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arcspace/go-cedar/process"
+)
+
+// CloseLatencyBuckets are the upper bounds of the close latency histogram (time from Closing() to Done()).
+var CloseLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+	time.Minute,
+}
+
+// Metrics is a process.Observer that tallies Context lifecycle events.
+// Since live Contexts are also tallied by label, Tasks with unbounded label cardinality (e.g. per-session labels)
+// will produce a correspondingly large number of series.
+type Metrics struct {
+	// If set, live Contexts are tallied by this rather than by Label() (e.g. PathLabel); set before registering.
+	LabelOf func(ctx process.Context) string
+
+	// Task.Tags keys by which live Contexts are also tallied (e.g. "component"); set before registering.
+	// Since each distinct tag value produces a series, only tags with bounded cardinality should be listed.
+	TagKeys []string
+
+	mu           sync.Mutex
+	liveLabels   map[int64]string // label each live Context was tallied under, keyed by ContextID
+	started      uint64
+	closed       uint64
+	liveByLabel  map[string]int64
+	liveByTag    map[string]int64 // keyed by "key=value"
+	latencyCount []uint64         // per CloseLatencyBuckets, plus a final +Inf bucket
+	latencySum   time.Duration
+}
+
+// Snapshot is a point-in-time copy of Metrics.
+type Snapshot struct {
+	Started          uint64           `json:"started"`
+	Closed           uint64           `json:"closed"` // Contexts started since registering that have since reached Done()
+	Live             int64            `json:"live"`
+	LiveByLabel      map[string]int64 `json:"live_by_label"`
+	LiveByTag        map[string]int64 `json:"live_by_tag,omitempty"` // keyed by "key=value" for each of Metrics.TagKeys
+	CloseLatencySum  time.Duration    `json:"close_latency_sum"`
+	CloseLatencyHist []uint64         `json:"close_latency_hist"` // non-cumulative counts per CloseLatencyBuckets, plus +Inf
+}
+
+// Install creates a new Metrics and registers it via process.AddObserver().
+func Install() (m *Metrics, uninstall func()) {
+	m = New()
+	return m, process.AddObserver(m)
+}
+
+// New returns a new Metrics that must be registered via process.AddObserver() to tally events.
+func New() *Metrics {
+	return &Metrics{
+		liveByLabel:  make(map[string]int64),
+		liveLabels:   make(map[int64]string),
+		liveByTag:    make(map[string]int64),
+		latencyCount: make([]uint64, len(CloseLatencyBuckets)+1),
+	}
+}
+
+func (m *Metrics) OnContextStarted(ctx process.Context) {
+	label := ctx.Label()
+	if m.LabelOf != nil {
+		label = m.LabelOf(ctx)
+	}
+
+	m.mu.Lock()
+	m.started++
+	m.liveByLabel[label]++
+	m.liveLabels[ctx.ContextID()] = label
+	m.tallyTags(ctx, 1)
+	m.mu.Unlock()
+}
+
+// tallyTags adds delta to the live count of each of the given Context's tags listed in TagKeys.
+// m.mu must be held.
+func (m *Metrics) tallyTags(ctx process.Context, delta int64) {
+	tags := ctx.Tags()
+	if len(tags) == 0 {
+		return
+	}
+	for _, key := range m.TagKeys {
+		if val, ok := tags[key]; ok {
+			tag := key + "=" + val
+			if m.liveByTag[tag] += delta; m.liveByTag[tag] <= 0 {
+				delete(m.liveByTag, tag)
+			}
+		}
+	}
+}
+
+// PathLabel returns the '/' separated labels from the given Context's root down to it (e.g. "root/session-A/reader"),
+// which is suitable for Metrics.LabelOf so that like-labelled Contexts in different subtrees are tallied separately.
+func PathLabel(ctx process.Context) string {
+	path := ctx.ContextPath()
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if j := strings.LastIndexByte(part, '#'); j >= 0 {
+			parts[i] = part[:j]
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func (m *Metrics) OnContextClosing(ctx process.Context) {}
+
+func (m *Metrics) OnContextDone(ctx process.Context) {
+	latency := ctx.Stats().CloseLatency
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Contexts started before this Metrics was registered were never tallied, so they're ignored
+	label, tallied := m.liveLabels[ctx.ContextID()]
+	if !tallied {
+		return
+	}
+	delete(m.liveLabels, ctx.ContextID())
+
+	m.closed++
+	if m.liveByLabel[label]--; m.liveByLabel[label] <= 0 {
+		delete(m.liveByLabel, label)
+	}
+	m.tallyTags(ctx, -1)
+
+	m.latencySum += latency
+	i := sort.Search(len(CloseLatencyBuckets), func(i int) bool {
+		return latency <= CloseLatencyBuckets[i]
+	})
+	m.latencyCount[i]++
+}
+
+func (m *Metrics) Snapshot() Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snap := Snapshot{
+		Started:          m.started,
+		Closed:           m.closed,
+		LiveByLabel:      make(map[string]int64, len(m.liveByLabel)),
+		CloseLatencySum:  m.latencySum,
+		CloseLatencyHist: append([]uint64(nil), m.latencyCount...),
+	}
+	for label, n := range m.liveByLabel {
+		snap.LiveByLabel[label] = n
+		snap.Live += n
+	}
+	if len(m.liveByTag) > 0 {
+		snap.LiveByTag = make(map[string]int64, len(m.liveByTag))
+		for tag, n := range m.liveByTag {
+			snap.LiveByTag[tag] = n
+		}
+	}
+	return snap
+}
+
+// PublishExpvar publishes this Metrics' Snapshot as an expvar with the given name (e.g. "process").
+func (m *Metrics) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return m.Snapshot()
+	}))
+}
+
+// ServeHTTP serves this Metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.WritePrometheus(w)
+}
+
+// WritePrometheus writes this Metrics in the Prometheus text exposition format.
+func (m *Metrics) WritePrometheus(out io.Writer) {
+	snap := m.Snapshot()
+
+	fmt.Fprintf(out, "# HELP cedar_contexts_started_total Contexts started.\n# TYPE cedar_contexts_started_total counter\n")
+	fmt.Fprintf(out, "cedar_contexts_started_total %d\n", snap.Started)
+	fmt.Fprintf(out, "# HELP cedar_contexts_closed_total Contexts that reached Done().\n# TYPE cedar_contexts_closed_total counter\n")
+	fmt.Fprintf(out, "cedar_contexts_closed_total %d\n", snap.Closed)
+	fmt.Fprintf(out, "# HELP cedar_contexts_live Contexts started but not yet Done().\n# TYPE cedar_contexts_live gauge\n")
+	fmt.Fprintf(out, "cedar_contexts_live %d\n", snap.Live)
+
+	fmt.Fprintf(out, "# HELP cedar_contexts_live_by_label Live contexts by label.\n# TYPE cedar_contexts_live_by_label gauge\n")
+	labels := make([]string, 0, len(snap.LiveByLabel))
+	for label := range snap.LiveByLabel {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		fmt.Fprintf(out, "cedar_contexts_live_by_label{label=%s} %d\n", quoteLabel(label), snap.LiveByLabel[label])
+	}
+
+	if len(snap.LiveByTag) > 0 {
+		fmt.Fprintf(out, "# HELP cedar_contexts_live_by_tag Live contexts by tag (see Metrics.TagKeys).\n# TYPE cedar_contexts_live_by_tag gauge\n")
+		tags := make([]string, 0, len(snap.LiveByTag))
+		for tag := range snap.LiveByTag {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			key, val, _ := strings.Cut(tag, "=")
+			fmt.Fprintf(out, "cedar_contexts_live_by_tag{tag=%s,value=%s} %d\n", quoteLabel(key), quoteLabel(val), snap.LiveByTag[tag])
+		}
+	}
+
+	fmt.Fprintf(out, "# HELP cedar_close_latency_seconds Time from Closing() to Done().\n# TYPE cedar_close_latency_seconds histogram\n")
+	var cumulative uint64
+	for i, n := range snap.CloseLatencyHist {
+		cumulative += n
+		le := "+Inf"
+		if i < len(CloseLatencyBuckets) {
+			le = strconv.FormatFloat(CloseLatencyBuckets[i].Seconds(), 'g', -1, 64)
+		}
+		fmt.Fprintf(out, "cedar_close_latency_seconds_bucket{le=%q} %d\n", le, cumulative)
+	}
+	fmt.Fprintf(out, "cedar_close_latency_seconds_sum %g\n", snap.CloseLatencySum.Seconds())
+	fmt.Fprintf(out, "cedar_close_latency_seconds_count %d\n", cumulative)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(val string) string {
+	return `"` + labelEscaper.Replace(val) + `"`
+}
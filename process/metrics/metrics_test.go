@@ -0,0 +1,114 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arcspace/go-cedar/process"
+	"github.com/arcspace/go-cedar/process/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	m, uninstall := metrics.Install()
+	defer uninstall()
+
+	p, _ := process.Start(&process.Task{Label: "root"})
+	p.StartChild(&process.Task{Label: "session"})
+	p.StartChild(&process.Task{Label: "session"})
+
+	snap := m.Snapshot()
+	require.Equal(t, uint64(3), snap.Started)
+	require.Equal(t, int64(3), snap.Live)
+	require.Equal(t, int64(2), snap.LiveByLabel["session"])
+
+	p.Close()
+	<-p.Done()
+
+	snap = m.Snapshot()
+	require.Equal(t, uint64(3), snap.Closed)
+	require.Equal(t, int64(0), snap.Live)
+	require.Empty(t, snap.LiveByLabel)
+
+	out := &strings.Builder{}
+	m.WritePrometheus(out)
+	require.Contains(t, out.String(), "cedar_contexts_started_total 3\n")
+	require.Contains(t, out.String(), "cedar_close_latency_seconds_count 3\n")
+}
+
+func TestInstallWhileRunning(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	early, _ := p.StartChild(&process.Task{Label: "session", Tags: map[string]string{"component": "api"}})
+
+	m := metrics.New()
+	m.TagKeys = []string{"component"}
+	uninstall := process.AddObserver(m)
+	defer uninstall()
+	p.StartChild(&process.Task{Label: "session", Tags: map[string]string{"component": "api"}})
+
+	// Contexts started before registering are ignored once done, so they can't mask those that are live
+	early.Close()
+	<-early.Done()
+	snap := m.Snapshot()
+	require.Equal(t, uint64(1), snap.Started)
+	require.Equal(t, uint64(0), snap.Closed)
+	require.Equal(t, int64(1), snap.Live)
+	require.Equal(t, int64(1), snap.LiveByLabel["session"])
+	require.Equal(t, int64(1), snap.LiveByTag["component=api"])
+
+	p.Close()
+	<-p.Done()
+	snap = m.Snapshot()
+	require.Equal(t, uint64(1), snap.Closed)
+	require.Equal(t, int64(0), snap.Live)
+	require.Empty(t, snap.LiveByLabel)
+
+	out := &strings.Builder{}
+	m.WritePrometheus(out)
+	require.Contains(t, out.String(), "cedar_contexts_live 0\n")
+}
+
+func TestPathLabel(t *testing.T) {
+	m := metrics.New()
+	m.LabelOf = metrics.PathLabel
+	uninstall := process.AddObserver(m)
+	defer uninstall()
+
+	p, _ := process.Start(&process.Task{Label: "root"})
+	for _, id := range []string{"A", "B"} {
+		session, _ := p.StartChild(&process.Task{Label: "session-%s", LabelArgs: []any{id}})
+		session.StartChild(&process.Task{Label: "reader"})
+	}
+
+	snap := m.Snapshot()
+	require.Equal(t, int64(1), snap.LiveByLabel["root/session-A/reader"])
+	require.Equal(t, int64(1), snap.LiveByLabel["root/session-B/reader"])
+
+	p.Close()
+	<-p.Done()
+	require.Empty(t, m.Snapshot().LiveByLabel)
+}
+
+func TestTagKeys(t *testing.T) {
+	m := metrics.New()
+	m.TagKeys = []string{"component"}
+	uninstall := process.AddObserver(m)
+	defer uninstall()
+
+	p, _ := process.Start(&process.Task{Label: "root"})
+	p.StartChild(&process.Task{Label: "a", Tags: map[string]string{"component": "ingest", "owner": "x"}})
+	p.StartChild(&process.Task{Label: "b", Tags: map[string]string{"component": "ingest"}})
+	p.StartChild(&process.Task{Label: "c", Tags: map[string]string{"component": "api"}})
+
+	snap := m.Snapshot()
+	require.Equal(t, map[string]int64{"component=ingest": 2, "component=api": 1}, snap.LiveByTag)
+
+	out := &strings.Builder{}
+	m.WritePrometheus(out)
+	require.Contains(t, out.String(), `cedar_contexts_live_by_tag{tag="component",value="ingest"} 2`)
+
+	p.Close()
+	<-p.Done()
+	require.Empty(t, m.Snapshot().LiveByTag)
+}
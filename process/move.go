@@ -0,0 +1,92 @@
+package process
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// gMoveMu serializes MoveTo() calls, which are the only place two subsMu locks are held at once.
+var gMoveMu sync.Mutex
+
+// getParent returns this Context's parent, which can change via MoveTo().
+func (p *ctx) getParent() *ctx {
+	return (*ctx)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&p.parent))))
+}
+
+func (p *ctx) setParent(parent *ctx) {
+	atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&p.parent)), unsafe.Pointer(parent))
+}
+
+// lockParent locks and returns this Context's parent such that it can't be changed by MoveTo() until its subsMu is unlocked.
+func (p *ctx) lockParent() *ctx {
+	for {
+		parent := p.getParent()
+		parent.subsMu.Lock()
+		if parent == p.getParent() {
+			return parent
+		}
+		parent.subsMu.Unlock()
+	}
+}
+
+func (p *ctx) MoveTo(newParent Context) error {
+	dst, ok := asCtx(newParent)
+	if !ok || dst == nil {
+		return ErrBadParent
+	}
+
+	gMoveMu.Lock()
+	defer gMoveMu.Unlock()
+
+	src := p.getParent()
+	if src == nil {
+		return ErrBadParent
+	}
+	if src == dst {
+		return nil
+	}
+	for ci := dst; ci != nil; ci = ci.getParent() {
+		if ci == p {
+			return ErrBadParent
+		}
+	}
+
+	src.subsMu.Lock()
+	dst.subsMu.Lock()
+	var err error
+	if atomic.LoadInt32(&p.state) != Running {
+		err = ErrClosing
+	} else if atomic.LoadInt32(&dst.state) != Running || dst.draining {
+		err = ErrClosing
+	} else {
+		src.removeChild(p)
+		dst.busy.Add(1)
+		dst.idle = false
+		dst.addChild(p)
+		p.setParent(dst)
+	}
+	dst.subsMu.Unlock()
+
+	closeSrc := false
+	drained := false
+	if err == nil {
+		closeSrc = src.numChildren == 0 && src.task.IdleClose > 0
+		drained = src.draining && src.numChildren == 0
+	}
+	src.subsMu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	// src is no longer waiting on p, mirroring the end of runClose()
+	src.busy.Done()
+	if closeSrc {
+		src.CloseWhenIdle(src.task.IdleClose)
+	}
+	if drained {
+		src.Close()
+	}
+	return nil
+}
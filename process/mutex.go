@@ -0,0 +1,81 @@
+package process
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Mutex is a mutual exclusion lock whose Lock() gives up once a given Context starts closing.
+// This prevents a closing worker from blocking forever on a lock held by a wedged (or already closed) sibling.
+// The zero value is an unlocked Mutex.
+type Mutex struct {
+	init sync.Once
+	ch   chan struct{} // holds a token while locked
+}
+
+func (m *Mutex) slot() chan struct{} {
+	m.init.Do(func() {
+		m.ch = make(chan struct{}, 1)
+	})
+	return m.ch
+}
+
+// Lock blocks until the Mutex is acquired, returning ErrClosing (without acquiring it) if ctx starts closing first.
+func (m *Mutex) Lock(ctx Context) error {
+	ch := m.slot()
+	select {
+	case ch <- struct{}{}:
+		return nil
+	default:
+	}
+	select {
+	case ch <- struct{}{}:
+		return nil
+	case <-ctx.Closing():
+		return ErrClosing
+	}
+}
+
+// TryLock acquires the Mutex if it is unlocked, returning false otherwise.
+func (m *Mutex) TryLock() bool {
+	select {
+	case m.slot() <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Unlock releases the Mutex, which may have been acquired by a different goroutine.
+// It panics if the Mutex is not locked.
+func (m *Mutex) Unlock() {
+	select {
+	case <-m.slot():
+	default:
+		panic("process: Unlock of unlocked Mutex")
+	}
+}
+
+// Once performs an action exactly once, where callers waiting on an action in progress give up once a given Context starts closing.
+// The zero value is ready to use.
+type Once struct {
+	mu   Mutex
+	done int32
+}
+
+// Do calls fn if no call to Do has yet completed it, else returns immediately.
+// If fn is running in another goroutine, Do waits for it to complete, returning ErrClosing if ctx starts closing first.
+func (o *Once) Do(ctx Context, fn func()) error {
+	if atomic.LoadInt32(&o.done) != 0 {
+		return nil
+	}
+	if err := o.mu.Lock(ctx); err != nil {
+		return err
+	}
+	defer o.mu.Unlock()
+	if o.done == 0 {
+		defer atomic.StoreInt32(&o.done, 1)
+		fn()
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package process
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Observer is notified of lifecycle events of every Context, allowing integrations such as tracing and metrics
+// without instrumenting each Task.  Calls are made synchronously from the goroutine driving the given Context,
+// so implementations should return promptly.
+type Observer interface {
+
+	// Called once a Context is added to its parent but before its Task.OnStart is called.
+	OnContextStarted(ctx Context)
+
+	// Called once Closing() has fired, before Task.OnClosing is called.
+	OnContextClosing(ctx Context)
+
+	// Called after Task.OnClosed, immediately before Done() is released.
+	OnContextDone(ctx Context)
+}
+
+var (
+	gObserversMu sync.Mutex
+	gObservers   atomic.Value // []Observer, replaced on each change
+)
+
+// AddObserver registers the given Observer to be notified of all Contexts' lifecycle events from here forward.
+// The returned func removes the Observer.
+func AddObserver(observer Observer) (remove func()) {
+	gObserversMu.Lock()
+	defer gObserversMu.Unlock()
+
+	prev, _ := gObservers.Load().([]Observer)
+	gObservers.Store(append(append([]Observer(nil), prev...), observer))
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			gObserversMu.Lock()
+			defer gObserversMu.Unlock()
+
+			prev, _ := gObservers.Load().([]Observer)
+			observers := make([]Observer, 0, len(prev))
+			for _, oi := range prev {
+				if oi != observer {
+					observers = append(observers, oi)
+				}
+			}
+			gObservers.Store(observers)
+		})
+	}
+}
+
+func observers() []Observer {
+	observers, _ := gObservers.Load().([]Observer)
+	return observers
+}
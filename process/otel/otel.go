@@ -0,0 +1,90 @@
+// Package otel traces process.Context lifecycles with OpenTelemetry, opening one span per Context.
+package otel
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/arcspace/go-cedar/process"
+)
+
+const instrumentationName = "github.com/arcspace/go-cedar/process"
+
+// Install registers a process.Observer that opens a span (from the given TracerProvider) when each Context starts
+// and ends it once the Context is Done().  Spans are nested according to the context tree and are annotated with
+// the Context's label and ID.  The returned func uninstalls the tracer (spans already open are still ended).
+func Install(provider trace.TracerProvider) (uninstall func()) {
+	return process.AddObserver(&tracer{
+		tracer: provider.Tracer(instrumentationName),
+	})
+}
+
+// SpanFromContext returns the span of the given Context (or its nearest traced ancestor).
+// If there is none, a non-recording span is returned.
+func SpanFromContext(ctx process.Context) trace.Span {
+	if cs, ok := ctx.Value(spanKey{}).(*ctxSpan); ok {
+		return cs.span
+	}
+	return trace.SpanFromContext(context.Background())
+}
+
+// ContextWithSpan returns a std context.Context carrying the span of the given Context, for use with
+// libraries that start child spans via trace.SpanFromContext().
+func ContextWithSpan(ctx process.Context) context.Context {
+	return trace.ContextWithSpan(context.Background(), SpanFromContext(ctx))
+}
+
+type spanKey struct{}
+
+// ctxSpan pairs a span with the Context it was started for (since Value() lookups fall through to ancestors).
+type ctxSpan struct {
+	contextID int64
+	span      trace.Span
+}
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+func ownSpan(ctx process.Context) trace.Span {
+	if cs, ok := ctx.Value(spanKey{}).(*ctxSpan); ok && cs.contextID == ctx.ContextID() {
+		return cs.span
+	}
+	return nil
+}
+
+func (t *tracer) OnContextStarted(ctx process.Context) {
+	_, span := t.tracer.Start(ContextWithSpan(ctx), ctx.Label(),
+		trace.WithAttributes(
+			attribute.String("cedar.label", ctx.Label()),
+			attribute.Int64("cedar.id", ctx.ContextID()),
+		),
+	)
+	ctx.SetValue(spanKey{}, &ctxSpan{
+		contextID: ctx.ContextID(),
+		span:      span,
+	})
+}
+
+func (t *tracer) OnContextClosing(ctx process.Context) {
+	if span := ownSpan(ctx); span != nil {
+		span.AddEvent("closing")
+	}
+}
+
+func (t *tracer) OnContextDone(ctx process.Context) {
+	span := ownSpan(ctx)
+	if span == nil {
+		return
+	}
+	if err := ctx.Cause(); err != nil && !errors.Is(err, context.Canceled) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.SetAttributes(attribute.String("cedar.close_kind", ctx.CloseKind().String()))
+	span.End()
+}
@@ -0,0 +1,133 @@
+package otel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/arcspace/go-cedar/process"
+	"github.com/arcspace/go-cedar/process/otel"
+)
+
+func install(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(otel.Install(provider))
+	return recorder
+}
+
+// ended returns the ended span having the given name.
+func ended(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+	t.Fatalf("no ended span named %q", name)
+	return nil
+}
+
+func attrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestSpans(t *testing.T) {
+	recorder := install(t)
+
+	root, _ := process.Start(&process.Task{Label: "root"})
+	server, _ := root.StartChild(&process.Task{Label: "server"})
+	session, _ := server.StartChild(&process.Task{Label: "session"})
+
+	// Each Context's span is live until it's done
+	require.True(t, otel.SpanFromContext(session).IsRecording())
+	require.Equal(t, otel.SpanFromContext(session).SpanContext(), trace.SpanContextFromContext(otel.ContextWithSpan(session)))
+	require.Empty(t, recorder.Ended())
+
+	root.Close()
+	<-root.Done()
+	require.Len(t, recorder.Ended(), 3)
+
+	rootSpan := ended(t, recorder, "root")
+	serverSpan := ended(t, recorder, "server")
+	sessionSpan := ended(t, recorder, "session")
+
+	// Spans nest as the Contexts do, within one trace
+	require.False(t, rootSpan.Parent().IsValid())
+	require.Equal(t, rootSpan.SpanContext().SpanID(), serverSpan.Parent().SpanID())
+	require.Equal(t, serverSpan.SpanContext().SpanID(), sessionSpan.Parent().SpanID())
+	require.Equal(t, rootSpan.SpanContext().TraceID(), sessionSpan.SpanContext().TraceID())
+
+	for _, tc := range []struct {
+		span sdktrace.ReadOnlySpan
+		ctx  process.Context
+	}{
+		{rootSpan, root},
+		{serverSpan, server},
+		{sessionSpan, session},
+	} {
+		m := attrs(tc.span)
+		require.Equal(t, tc.ctx.Label(), m["cedar.label"].AsString())
+		require.Equal(t, tc.ctx.ContextID(), m["cedar.id"].AsInt64())
+		require.Equal(t, process.CloseKind_Cancelled.String(), m["cedar.close_kind"].AsString())
+
+		// An ordinary Close() (context.Canceled) isn't an error
+		require.Equal(t, codes.Unset, tc.span.Status().Code)
+		var events []string
+		for _, event := range tc.span.Events() {
+			events = append(events, event.Name)
+		}
+		require.Equal(t, []string{"closing"}, events)
+	}
+}
+
+func TestSpanError(t *testing.T) {
+	recorder := install(t)
+
+	root, _ := process.Start(&process.Task{Label: "root"})
+	defer root.Close()
+
+	errBroken := errors.New("broken pipe")
+	failed, _ := root.StartChild(&process.Task{
+		Label: "failed",
+		OnRunErr: func(ctx process.Context) error {
+			return errBroken
+		},
+	})
+	<-failed.Done()
+	require.Equal(t, process.CloseKind_Failed, failed.CloseKind())
+
+	span := ended(t, recorder, "failed")
+	require.Equal(t, codes.Error, span.Status().Code)
+	require.Equal(t, errBroken.Error(), span.Status().Description)
+	require.Equal(t, process.CloseKind_Failed.String(), attrs(span)["cedar.close_kind"].AsString())
+
+	var recorded []string
+	for _, event := range span.Events() {
+		if event.Name == "exception" {
+			for _, kv := range event.Attributes {
+				if kv.Key == "exception.message" {
+					recorded = append(recorded, kv.Value.AsString())
+				}
+			}
+		}
+	}
+	require.Equal(t, []string{errBroken.Error()}, recorded)
+
+	// context.Canceled, even when given explicitly, isn't an error
+	canceled, _ := root.StartChild(&process.Task{Label: "canceled"})
+	canceled.CloseWithError(context.Canceled)
+	<-canceled.Done()
+	require.Equal(t, codes.Unset, ended(t, recorder, "canceled").Status().Code)
+}
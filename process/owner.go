@@ -0,0 +1,87 @@
+package process
+
+import (
+	"reflect"
+	"sync"
+)
+
+// gOwners indexes all open Contexts that were started with a non-nil Task.Owner.
+var gOwners = struct {
+	sync.Mutex
+	byOwner map[interface{}]map[*ctx]struct{}
+}{
+	byOwner: make(map[interface{}]map[*ctx]struct{}),
+}
+
+// ForEachByOwner calls fn for each open Context whose Task.Owner equals the given owner.
+// fn is called without any internal locks held, so it is free to Close() or start children.
+func ForEachByOwner(owner interface{}, fn func(ctx Context)) {
+	for _, ci := range ownedBy(owner) {
+		fn(ci)
+	}
+}
+
+// CloseByOwner calls Close() on each open Context whose Task.Owner equals the given owner and returns how many were closed.
+// Contexts that were already closing are not counted.
+func CloseByOwner(owner interface{}) int {
+	closed := 0
+	for _, ci := range ownedBy(owner) {
+		if ci.(*ctx).startClosing(CloseKind_Cancelled, nil) {
+			closed++
+		}
+	}
+	return closed
+}
+
+func ownedBy(owner interface{}) []Context {
+	if !isValidOwner(owner) {
+		return nil
+	}
+
+	gOwners.Lock()
+	defer gOwners.Unlock()
+
+	set := gOwners.byOwner[owner]
+	owned := make([]Context, 0, len(set))
+	for ci := range set {
+		owned = append(owned, ci)
+	}
+	return owned
+}
+
+// isValidOwner returns true if owner can be used as a map key.
+func isValidOwner(owner interface{}) (valid bool) {
+	if owner == nil || !reflect.TypeOf(owner).Comparable() {
+		return false
+	}
+
+	// A comparable type can still hold uncomparable values (e.g. a slice in an interface field), which panic once compared.
+	// This also rejects a NaN, which would never match itself as a key.
+	defer func() {
+		if recover() != nil {
+			valid = false
+		}
+	}()
+	return owner == owner
+}
+
+func (p *ctx) addToOwnerIndex() {
+	gOwners.Lock()
+	set := gOwners.byOwner[p.task.Owner]
+	if set == nil {
+		set = make(map[*ctx]struct{})
+		gOwners.byOwner[p.task.Owner] = set
+	}
+	set[p] = struct{}{}
+	gOwners.Unlock()
+}
+
+func (p *ctx) removeFromOwnerIndex() {
+	gOwners.Lock()
+	set := gOwners.byOwner[p.task.Owner]
+	delete(set, p)
+	if len(set) == 0 {
+		delete(gOwners.byOwner, p.task.Owner)
+	}
+	gOwners.Unlock()
+}
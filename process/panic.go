@@ -0,0 +1,52 @@
+package process
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// DefaultOnPanic is called when a Task's OnStart or OnRun panics and the Task has no OnPanic set.
+// Set this to nil to instead have such panics propagate (and so abort the program).
+var DefaultOnPanic = func(ctx Context, recovered any, stack []byte) {
+	ctx.Errorf("panic: %v\n%s", recovered, stack)
+}
+
+// PanicError is the cause (see Context.Cause) of a Context closed because its OnStart or OnRun panicked.
+type PanicError struct {
+	Recovered any
+	Stack     []byte
+}
+
+func (err *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", err.Recovered)
+}
+
+// callRecover invokes fn, returning any recovered panic as a *PanicError after passing it to the panic handler.
+func (p *ctx) callRecover(fn func() error) (err error) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+
+		onPanic := p.task.OnPanic
+		if onPanic == nil {
+			onPanic = DefaultOnPanic
+		}
+		if onPanic == nil {
+			panic(recovered)
+		}
+
+		stack := debug.Stack()
+		onPanic(p, recovered, stack)
+		err = &PanicError{
+			Recovered: recovered,
+			Stack:     stack,
+		}
+		publishEvent(Event_Panicked, p, err)
+		p.record(Event_Panicked.String(), err.Error())
+	}()
+
+	chaosPanic(p)
+	return fn()
+}
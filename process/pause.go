@@ -0,0 +1,62 @@
+package process
+
+import "sync/atomic"
+
+// pausedBy returns the resume channel of the nearest paused Context among this Context and its ancestors (or nil if none are paused).
+func (p *ctx) pausedBy() <-chan struct{} {
+	for ci := p; ci != nil; ci = ci.getParent() {
+		ci.subsMu.Lock()
+		resumed := ci.resumed
+		ci.subsMu.Unlock()
+		if resumed != nil {
+			return resumed
+		}
+	}
+	return nil
+}
+
+func (p *ctx) Pause() {
+	p.subsMu.Lock()
+	paused := p.resumed == nil
+	if paused {
+		p.resumed = make(chan struct{})
+	}
+	p.subsMu.Unlock()
+
+	if paused {
+		p.record("paused", "")
+	}
+}
+
+func (p *ctx) Resume() {
+	p.subsMu.Lock()
+	resumed := p.resumed
+	p.resumed = nil
+	p.subsMu.Unlock()
+
+	if resumed != nil {
+		close(resumed)
+		p.record("resumed", "")
+	}
+}
+
+func (p *ctx) IsPaused() bool {
+	return p.pausedBy() != nil
+}
+
+func (p *ctx) Checkpoint() error {
+	for {
+		// The raw state is Running while OnStart runs (see State), so OnStart can checkpoint too
+		if atomic.LoadInt32(&p.state) != Running {
+			return ErrClosing
+		}
+		resumed := p.pausedBy()
+		if resumed == nil {
+			return nil
+		}
+		select {
+		case <-resumed:
+		case <-p.Closing():
+		}
+	}
+}
@@ -59,8 +59,8 @@ const (
 )
 
 func (p *Pool) OnContextStarted(ctx Context) error {
-	p.Context.Go("deliverAvailableItems", p.deliverAvailableItems)
-	p.Context.Go("handleItemsAwaitingRetry", p.handleItemsAwaitingRetry)
+	ctx.Go("deliverAvailableItems", p.deliverAvailableItems)
+	ctx.Go("handleItemsAwaitingRetry", p.handleItemsAwaitingRetry)
 	return nil
 }
 
@@ -194,8 +194,6 @@ func (p *Pool) handleItemsAwaitingRetry(ctx Context) {
 	ticker := time.NewTicker(p.retryInterval)
 	for {
 		select {
-		case <-p.Context.Done():
-			return
 		case <-ctx.Done():
 			return
 
@@ -47,7 +47,7 @@ func StartNewPoolWorker(name string, concurrency int, scheduler PoolWorkerSchedu
 }
 
 func (w *poolWorker) OnContextStarted(ctx Context) error {
-	_, err := w.StartChild(&Task{
+	_, err := ctx.StartChild(&Task{
 		Label: "poolWorker",
 		OnStart: w.pool.OnContextStarted,
 	})
@@ -56,7 +56,7 @@ func (w *poolWorker) OnContextStarted(ctx Context) error {
 	}
 
 	for i := 0; i < w.concurrency; i++ {
-		w.Context.Go(fmt.Sprintf("worker %v", i), func(ctx Context) {
+		ctx.Go(fmt.Sprintf("worker %v", i), func(ctx Context) {
 			for {
 				select {
 				case <-ctx.Done():
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"runtime/pprof"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -16,19 +19,64 @@ import (
 type ctx struct {
 	log.Logger
 
-	task Task
+	task      Task
+	labelOnce sync.Once // renders label if Task.LabelArgs is set
+	scheduled int32     // set while waiting to run a GoAt() fn
+	label     string
+	parent    *ctx // nil for a root; accessed atomically via getParent() once started (see MoveTo)
+	origin    *ctx // if Task.Detached, the Context that started this one (see Value())
 
+	taskRef  *taskRefHolder // accessed atomically, non-nil once set via SetTaskRef() (otherwise task.TaskRef is used)
+	valuesMu sync.RWMutex
+	values   map[interface{}]interface{} // see Value()
+
+	traceID        *string // accessed atomically (see TraceID)
+	clock          Clock
+	history        *history // non-nil if this Context or an ancestor sets Task.HistorySize
+	startTime      time.Time
+	deadline       time.Time // see Deadline()
 	id             int64
 	state          int32
-	idleClose      int32
-	idleCloseDelay time.Duration
-	idle           bool
-	chClosing      chan struct{}  // signals Close() has been called and close execution has begun.
-	chClosed       chan struct{}  // signals Close() has been called and all close execution is done.
-	err            error          // See context.Err() for spec
+	starting       int32 // set while OnStart runs, so State() reports Starting
+	closeKind      int32
+	unhealthy      int32
+	restarting     int32          // number of children pending restart
+	idleClose      int32          // set once CloseWhenIdle() has been called
+	restart        *restartState  // non-nil if Task.RestartPolicy is set
+	idleCloseDelay int64          // time.Duration, accessed atomically
+	idle           bool           // accessed under subsMu
+	registered     bool           // set if this Context or an ancestor sets Task.RegisterIDs (see LookupID)
+	draining       bool           // set by Drain(), accessed under subsMu
+	sealed         bool           // set once closing has progressed past where Task.Cleanup children can start, accessed under subsMu
+	profileLabels  bool           // set if this Context or an ancestor sets Task.ProfileLabels
+	closingAt      int64          // UnixNano when Close() was first called (or 0)
+	closedAt       int64          // UnixNano when the close sequence completed (or 0)
+	startDuration  int64          // time.Duration that OnStart took
+	runStartedAt   int64          // UnixNano when OnRun was called (or 0)
+	runDuration    int64          // time.Duration that OnRun took, once it has returned
+	numStarted     int64          // cumulative children started, accessed under subsMu
+	startPCs       []uintptr      // stack that started this Context (see StartStack)
+	closeGate      int32          // incremented by Close() and by StartChild() once setup is complete; the close sequence starts once both have
+	criticalDown   int32          // number of Task.Critical children that closed while this Context was running and have not been restarted
+	deadlineTimer  Timer          // non-nil if this Context has its own deadline
+	closing        latch          // signals Close() has been called and close execution has begun.
+	closed         latch          // signals Close() has been called and all close execution is done.
+	err            error          // cause passed to CloseWithError(); written once before closing fires
 	busy           sync.WaitGroup // blocks until all execution is complete
-	subsMu         sync.Mutex     // Locked when .subs is being accessed
-	subs           []Context
+	group          sync.WaitGroup // tracks children started via GoErr()
+	groupErr       error          // first error returned by a GoErr() child
+	groupErrOnce   sync.Once
+	reported       []error                 // see ReportError(), accessed under subsMu
+	subsMu         sync.Mutex              // Locked when children are being accessed
+	firstChild     *ctx                    // oldest open child
+	lastChild      *ctx                    // newest open child
+	numChildren    int                     // accessed under subsMu
+	resumed        chan struct{}           // non-nil while paused (see Pause), closed by Resume(), accessed under subsMu
+	prevSib        *ctx                    // next older sibling, accessed under parent.subsMu
+	nextSib        *ctx                    // next newer sibling, accessed under parent.subsMu
+	holds          map[*sync.Once]struct{} // outstanding HoldIdle() releases, accessed under subsMu
+	runDone        chan struct{}           // if OnRun is set, closed once OnRun has returned
+	runRelease     func()                  // releases OnRun's hold on busy (idempotent)
 }
 
 // Errors
@@ -36,43 +84,255 @@ var (
 	ErrAlreadyStarted = errors.New("already started")
 	ErrUnstarted      = errors.New("unstarted")
 	ErrClosed         = errors.New("closed")
+	ErrBadOwner       = errors.New("invalid owner")
+	ErrAlreadyRunning = errors.New("already running")
+	ErrClosing        = errors.New("closing")
+	ErrBadParent      = errors.New("invalid parent")
 )
 
 var gSpawnCounter = int64(0)
 
 func (p *ctx) Close() error {
-	first := atomic.CompareAndSwapInt32(&p.state, Running, Closing)
-	if first {
-		close(p.chClosing)
+	return p.closeAs(CloseKind_Cancelled, nil)
+}
+
+func (p *ctx) CloseWithError(err error) error {
+	return p.closeAs(CloseKind_Cancelled, err)
+}
+
+func (p *ctx) Complete() {
+	p.closeAs(CloseKind_Completed, nil)
+}
+
+func (p *ctx) Cause() error {
+	if !p.closing.isFired() {
+		return nil
 	}
+	if p.err == nil {
+		return context.Canceled
+	}
+	return p.err
+}
+
+func (p *ctx) CloseKind() CloseKind {
+	if atomic.LoadInt32(&p.state) == Running {
+		return CloseKind_Open
+	}
+	return CloseKind(atomic.LoadInt32(&p.closeKind))
+}
+
+// setCloseKind records why this Context closed unless a reason was already recorded.
+func (p *ctx) setCloseKind(kind CloseKind) {
+	atomic.CompareAndSwapInt32(&p.closeKind, int32(CloseKind_Open), int32(kind))
+}
+
+// closeAs initiates Close() for the given reason, where err (if non-nil) is the cause reported by Err() and Cause().
+func (p *ctx) closeAs(kind CloseKind, err error) error {
+	p.startClosing(kind, err)
 	return nil
 }
 
+// startClosing is closeAs() but returns true only if this call initiated closing (i.e. this Context was Running).
+func (p *ctx) startClosing(kind CloseKind, err error) (first bool) {
+	p.setCloseKind(kind)
+	first = atomic.CompareAndSwapInt32(&p.state, Running, Closing)
+	if first {
+		p.err = err
+		atomic.StoreInt64(&p.closingAt, p.clock.Now().UnixNano())
+		p.closing.fire()
+		p.launchClose()
+	}
+	return first
+}
+
+// launchClose starts the close sequence once it has been called by both closeAs() and startChild().
+// This ensures the close sequence never runs against a partially started Context, without parking a goroutine per Context.
+func (p *ctx) launchClose() {
+	if atomic.AddInt32(&p.closeGate, 1) == 2 {
+		go p.runClose()
+	}
+}
+
+func (p *ctx) Drain() {
+	p.subsMu.Lock()
+	p.draining = true
+	drained := p.numChildren == 0
+	p.subsMu.Unlock()
+
+	if drained {
+		p.Close()
+	}
+}
+
+// isDraining returns true if Drain() has been called.
+func (p *ctx) isDraining() bool {
+	p.subsMu.Lock()
+	defer p.subsMu.Unlock()
+	return p.draining
+}
+
+func (p *ctx) HoldIdle() (release func()) {
+	once := &sync.Once{}
+
+	p.subsMu.Lock()
+	defer p.subsMu.Unlock()
+
+	if atomic.LoadInt32(&p.state) != Running {
+		return func() {}
+	}
+
+	p.busy.Add(1)
+	p.idle = false
+	if p.holds == nil {
+		p.holds = make(map[*sync.Once]struct{})
+	}
+	p.holds[once] = struct{}{}
+
+	return func() {
+		once.Do(func() {
+			p.subsMu.Lock()
+			delete(p.holds, once)
+			p.subsMu.Unlock()
+			p.busy.Done()
+
+			if p.task.IdleClose > 0 {
+				p.CloseWhenIdle(p.task.IdleClose)
+			}
+		})
+	}
+}
+
+// isIdle returns true if no activity has occurred since idle detection was last set up by CloseWhenIdle().
+func (p *ctx) isIdle() bool {
+	p.subsMu.Lock()
+	defer p.subsMu.Unlock()
+	return p.idle
+}
+
+func (p *ctx) Touch() {
+	p.subsMu.Lock()
+	p.idle = false
+	p.subsMu.Unlock()
+}
+
+// releaseHolds releases all outstanding HoldIdle() holds.
+func (p *ctx) releaseHolds() {
+	p.subsMu.Lock()
+	holds := p.holds
+	p.holds = nil
+	p.subsMu.Unlock()
+
+	for once := range holds {
+		once.Do(p.busy.Done)
+	}
+}
+
+// closeChildren signals this Context's children to close as specified by Task.CloseOrder and their Task.ClosePriority.
+func (p *ctx) closeChildren() {
+	var buf [16]Context
+	children := p.GetChildren(buf[:0])
+
+	if !samePriority(children) {
+		// Sort a copy so that buf doesn't escape when all children share a priority (the common case)
+		sorted := append(make([]Context, 0, len(children)), children...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].(*ctx).task.ClosePriority > sorted[j].(*ctx).task.ClosePriority
+		})
+		for len(sorted) > 0 {
+			n := 1
+			for n < len(sorted) && sorted[n].(*ctx).task.ClosePriority == sorted[0].(*ctx).task.ClosePriority {
+				n++
+			}
+			p.closeBatch(sorted[:n])
+
+			// Cleanup children aren't signaled (see Task.Cleanup), so only wait on those that were
+			for _, ci := range sorted[:n] {
+				if !ci.(*ctx).task.Cleanup {
+					<-ci.Done()
+				}
+			}
+			sorted = sorted[n:]
+		}
+		return
+	}
+	p.closeBatch(children)
+}
+
+// samePriority returns true if all the given children have the same Task.ClosePriority.
+func samePriority(children []Context) bool {
+	for _, ci := range children {
+		if ci.(*ctx).task.ClosePriority != children[0].(*ctx).task.ClosePriority {
+			return false
+		}
+	}
+	return true
+}
+
+// closeBatch signals the given children to close as specified by Task.CloseOrder.
+func (p *ctx) closeBatch(children []Context) {
+	switch p.task.CloseOrder {
+	case CloseOrder_LIFO:
+		for i := len(children) - 1; i >= 0; i-- {
+			if child := children[i].(*ctx); !child.task.Cleanup {
+				child.closeAs(CloseKind_Cancelled, p.err)
+				<-child.Done()
+			}
+		}
+	default:
+		chaosShuffle(children)
+		for _, ci := range children {
+			if child := ci.(*ctx); !child.task.Cleanup {
+				child.closeAs(CloseKind_Cancelled, p.err)
+			}
+		}
+	}
+}
+
 func (p *ctx) CloseWhenIdle(delay time.Duration) {
 
 	// Allow subsequent calls to set a new delay
-	p.idleCloseDelay = delay
+	atomic.StoreInt64(&p.idleCloseDelay, int64(delay))
 
 	// Ensure only one timer for a ctx is every running
-	// Can this be folded into the main go routine in StartChild() to save a goroutine?
 	first := atomic.CompareAndSwapInt32(&p.idleClose, 0, 1)
 	if first {
+		publishEvent(Event_IdleCloseArmed, p, nil)
+		p.record(Event_IdleCloseArmed.String(), "")
 		go func() {
-			var timer *time.Timer
+			var timer Timer
+			sleep := func(d time.Duration) {
+				if timer == nil {
+					timer = p.clock.NewTimer(d)
+				} else {
+					timer.Reset(d)
+				}
+				select {
+				case <-timer.C():
+				case <-p.Closing():
+				}
+			}
 
 			for waiting := true; waiting; {
+				p.subsMu.Lock()
 				p.idle = true // setup idle detection
+				p.subsMu.Unlock()
 				p.busy.Wait() // wait until there is a chance of catching ctx idle
 
-				if delay := p.idleCloseDelay; delay > time.Microsecond {
-					if timer == nil {
-						timer = time.NewTimer(delay)
-					} else {
-						timer.Reset(delay)
+				if delay := time.Duration(atomic.LoadInt64(&p.idleCloseDelay)); delay > time.Microsecond {
+					sleep(delay)
+				}
+
+				// Never reap a Context younger than IdleCloseMin (e.g. a session still handshaking)
+				if min := p.task.IdleCloseMin; min > 0 {
+					if remain := p.startTime.Add(min).Sub(p.clock.Now()); remain > 0 {
+						sleep(remain)
 					}
-					select {
-					case <-timer.C:
-					case <-p.Closing():
+				}
+
+				// Allow OnIdle to veto the close, in effect calling Touch()
+				if p.task.OnIdle != nil && p.isIdle() && atomic.LoadInt32(&p.state) == Running {
+					if p.task.OnIdle(p) {
+						continue
 					}
 				}
 
@@ -80,7 +340,7 @@ func (p *ctx) CloseWhenIdle(delay time.Duration) {
 				// Note in the case that we're closing, the below has no effect
 				p.subsMu.Lock()
 				if p.idle {
-					p.Close()
+					p.closeAs(CloseKind_Idle, nil)
 					waiting = false
 				}
 				p.subsMu.Unlock()
@@ -90,27 +350,120 @@ func (p *ctx) CloseWhenIdle(delay time.Duration) {
 }
 
 func (p *ctx) Deadline() (deadline time.Time, ok bool) {
-	return time.Time{}, false
+	return p.deadline, !p.deadline.IsZero()
 }
 
-func (p *ctx) Err() error {
-	select {
-	case <-p.Done():
-		if p.err == nil {
-			return context.Canceled
+// initDeadline sets this Context's deadline to the earliest of its Task's Deadline and Timeout and its parent's deadline.
+// If this Context's own deadline is earliest, it returns the duration until its deadline is reached.
+func (p *ctx) initDeadline(parent *ctx) (until time.Duration, hasOwn bool) {
+	own := p.task.Deadline
+	if p.task.Timeout > 0 {
+		if timeout := p.startTime.Add(p.task.Timeout); own.IsZero() || timeout.Before(own) {
+			own = timeout
 		}
-		return p.err
-	default:
+	}
+
+	var inherited time.Time
+	if parent != nil {
+		inherited = parent.deadline
+	}
+	if std := p.task.Context; std != nil {
+		if stdDeadline, ok := std.Deadline(); ok && (inherited.IsZero() || stdDeadline.Before(inherited)) {
+			inherited = stdDeadline
+		}
+	}
+
+	if !own.IsZero() && (inherited.IsZero() || own.Before(inherited)) {
+		p.deadline = own
+		return own.Sub(p.startTime), true
+	}
+	p.deadline = inherited
+	return 0, false
+}
+
+func (p *ctx) Err() error {
+	if !p.closed.isFired() {
 		return nil
 	}
+	if p.err == nil {
+		return context.Canceled
+	}
+	return p.err
 }
 
+// Value returns the value associated with key by the nearest Context (starting with this one) that has a value for it.
+// For each Context, Task.Values and SetValue() are consulted, followed by Task.Context (if set).
 func (p *ctx) Value(key interface{}) interface{} {
+	if _, isTraceKey := key.(traceIDKey); isTraceKey {
+		if traceID := p.TraceID(); traceID != "" {
+			return traceID
+		}
+		return nil
+	}
+	for ci := p; ci != nil; ci = ci.valueParent() {
+		ci.valuesMu.RLock()
+		val, ok := ci.values[key]
+		ci.valuesMu.RUnlock()
+		if ok {
+			return val
+		}
+		if std := ci.task.Context; std != nil {
+			if val := std.Value(key); val != nil {
+				return val
+			}
+		}
+	}
 	return nil
 }
 
-func (p *ctx) TaskRef() interface{} {
-	return p.task.TaskRef
+// valueParent returns the Context that Value() consults after this one.
+func (p *ctx) valueParent() *ctx {
+	if p.origin != nil {
+		return p.origin
+	}
+	return p.getParent()
+}
+
+// root returns the root of this Context's tree.
+func (p *ctx) root() *ctx {
+	for parent := p.getParent(); parent != nil; parent = p.getParent() {
+		p = parent
+	}
+	return p
+}
+
+func (p *ctx) SetValue(key, val interface{}) {
+	p.valuesMu.Lock()
+	if p.values == nil {
+		p.values = make(map[interface{}]interface{})
+	}
+	p.values[key] = val
+	p.valuesMu.Unlock()
+}
+
+func (p *ctx) Owner() interface{} {
+	return p.task.Owner
+}
+
+func (p *ctx) Tags() map[string]string {
+	return p.task.Tags
+}
+
+// LogPaths, if set, causes Contexts to label their log output with ContextPath() rather than Label().
+// Since a Context's log label is rendered when it first logs, this should be set before Contexts are started.
+var LogPaths = false
+
+// logLabel returns the label used for this Context's log output.
+func (p *ctx) logLabel() string {
+	label := p.Label()
+	if LogPaths {
+		label = p.ContextPath()
+	}
+	traceID := p.TraceID()
+	if traceID == "" {
+		return label
+	}
+	return fmt.Sprintf("%s trace=%s", label, traceID)
 }
 
 func (p *ctx) ContextID() int64 {
@@ -118,156 +471,507 @@ func (p *ctx) ContextID() int64 {
 }
 
 func (p *ctx) Label() string {
-	return p.task.Label
+	if p.task.LabelArgs == nil {
+		return p.task.Label
+	}
+	p.labelOnce.Do(func() {
+		p.label = fmt.Sprintf(p.task.Label, p.task.LabelArgs...)
+	})
+	return p.label
 }
 
-func printContextTree(ctx Context, out *strings.Builder, depth int) {
-	out.WriteString(fmt.Sprintf("%s%03d %s\n", strings.Repeat("    ", depth), ctx.ContextID(), ctx.Label()))
+func (p *ctx) ContextPath() string {
+	var path []string
+	for ci := p; ci != nil; ci = ci.getParent() {
+		path = append(path, fmt.Sprintf("%s#%d", ci.Label(), ci.id))
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return strings.Join(path, "/")
+}
 
-	var subBuf [20]Context
-	children := ctx.GetChildren(subBuf[:0])
-	for _, ci := range children {
-		printContextTree(ci, out, depth+1)
+func (p *ctx) State() int32 {
+	state := atomic.LoadInt32(&p.state)
+	if state == Running && atomic.LoadInt32(&p.starting) != 0 {
+		return Starting
+	}
+	return state
+}
+
+func (p *ctx) StartTime() time.Time {
+	return p.startTime
+}
+
+func (p *ctx) GetChildren(in []Context, opts ...ChildOption) []Context {
+	if len(opts) == 0 {
+		p.subsMu.Lock()
+		defer p.subsMu.Unlock()
+		for child := p.firstChild; child != nil; child = child.nextSib {
+			in = append(in, child)
+		}
+		return in
+	}
+
+	var filter childFilter
+	for _, opt := range opts {
+		opt(&filter)
+	}
+	return p.appendChildren(in, &filter)
+}
+
+// appendChildren appends the children of this Context that pass the given filter, recursing if filter.recursive is set.
+// Each Context's subsMu is only held while its own children are visited.
+func (p *ctx) appendChildren(in []Context, filter *childFilter) []Context {
+	var subBuf [20]*ctx
+	subs := subBuf[:0]
+
+	p.subsMu.Lock()
+	for child := p.firstChild; child != nil; child = child.nextSib {
+		if filter.matches(child) {
+			in = append(in, child)
+		}
+		if filter.recursive {
+			subs = append(subs, child)
+		}
+	}
+	p.subsMu.Unlock()
+
+	for _, child := range subs {
+		in = child.appendChildren(in, filter)
 	}
+	return in
 }
 
-func (p *ctx) GetChildren(in []Context) []Context {
+func (p *ctx) GetChild(label string) Context {
 	p.subsMu.Lock()
 	defer p.subsMu.Unlock()
-	return append(in, p.subs...)
+	for child := p.firstChild; child != nil; child = child.nextSib {
+		if child.Label() == label {
+			return child
+		}
+	}
+	return nil
 }
 
 func (p *ctx) ChildCount() int {
 	p.subsMu.Lock()
 	defer p.subsMu.Unlock()
-	return len(p.subs)
+	return p.numChildren
+}
+
+// runningChild returns the child having the given label that is not yet closing (or nil).
+// p.subsMu must be held.
+func (p *ctx) runningChild(label string) Context {
+	for child := p.firstChild; child != nil; child = child.nextSib {
+		if child.Label() == label && atomic.LoadInt32(&child.state) == Running {
+			return child
+		}
+	}
+	return nil
 }
 
 // StartChild starts the given child Context as a "sub" process.
 func (p *ctx) StartChild(task *Task) (Context, error) {
+	return p.startChild(task, nil)
+}
+
+// startChild starts a child for the given Task, where rs is non-nil when restarting a previously closed child.
+func (p *ctx) startChild(task *Task, rs *restartState) (Context, error) {
 	child := &ctx{
-		state:     Running,
-		id:        atomic.AddInt64(&gSpawnCounter, 1),
-		chClosing: make(chan struct{}),
-		chClosed:  make(chan struct{}),
+		state: Running,
+		id:    atomic.AddInt64(&gSpawnCounter, 1),
 	}
 	if task != nil {
 		child.task = *task
+		if task.OnStart != nil {
+			child.starting = 1
+		}
+	}
+
+	// A detached child is started under the root but otherwise inherits from the Context that started it
+	origin := p
+	if p != nil && child.task.Detached {
+		p = p.root()
+		if p != origin {
+			child.origin = origin
+		}
+	}
+
+	child.clock = clockFor(&child.task, p)
+	child.profileLabels = child.task.ProfileLabels || (p != nil && p.profileLabels)
+	if size := child.task.HistorySize; size > 0 {
+		child.history = newHistory(size)
+	} else if p != nil && p.history != nil {
+		child.history = newHistory(len(p.history.entries))
+	}
+	child.startTime = child.clock.Now()
+	if len(child.task.Values) > 0 {
+		child.values = make(map[interface{}]interface{}, len(child.task.Values))
+		for key, val := range child.task.Values {
+			child.values[key] = val
+		}
 	}
-	if child.task.Label == "" {
+	if !child.task.runAt.IsZero() {
+		child.scheduled = 1
+	}
+	if child.task.Label == "" && child.task.LabelArgs == nil {
 		child.task.Label = fmt.Sprintf("ctx_%d", child.id)
 	}
-	child.Logger = log.NewLogger(child.task.Label)
+	if p != nil {
+		child.parent = p
+		child.traceID = origin.loadTraceID()
+		child.registered = p.registered
+	}
+	if child.task.RegisterIDs {
+		child.registered = true
+	}
+	if child.task.TraceID != "" {
+		child.traceID = &child.task.TraceID
+	} else if child.traceID == nil && child.task.Context != nil {
+		if traceID := TraceIDFrom(child.task.Context); traceID != "" {
+			child.traceID = &traceID
+		}
+	}
+	if p != nil {
+		if child.task.LabelArgs != nil || LogPaths {
+			child.Logger = log.NewLazyChildLogger(origin.Logger, child.logLabel)
+		} else {
+			child.Logger = log.NewChildLogger(origin.Logger, child.logLabel())
+		}
+	} else {
+		child.Logger = log.NewLogger(child.logLabel())
+	}
+	if child.history != nil {
+		child.Logger = log.WithHook(child.Logger, child.record)
+	}
+
+	if child.task.Owner != nil && !isValidOwner(child.task.Owner) {
+		return nil, ErrBadOwner
+	}
+
+	if child.task.RestartPolicy != RestartNever {
+		if rs == nil {
+			rs = newRestartState(&child.task)
+		}
+		child.restart = rs
+	}
+
+	// OnRun is tracked from the outset so that Close() can't race ahead of it
+	if child.task.OnRun != nil || child.task.OnRunErr != nil {
+		var once sync.Once
+		child.busy.Add(1)
+		child.runDone = make(chan struct{})
+		child.runRelease = func() { once.Do(child.busy.Done) }
+	}
+
+	child.captureStartStack()
 
 	// If a parent is given, add the child to the parent's list of children.
 	if p != nil {
 
 		var err error
+		var existing Context
 		p.subsMu.Lock()
-		if p.state == Running {
+		switch state := atomic.LoadInt32(&p.state); {
+		case state == Closing && child.task.Cleanup && !p.sealed:
+		case state == Closing:
+			err = ErrClosing
+		case state == Closed:
+			err = ErrClosed
+		case state != Running:
+			err = ErrUnstarted
+		case p.draining:
+			err = ErrClosing
+		}
+		if err == nil && child.task.Unique {
+			existing = p.runningChild(child.Label())
+		}
+		if err == nil && existing == nil {
 			p.busy.Add(1)
 			p.idle = false
-			p.subs = append(p.subs, child)
-		} else {
-			err = ErrUnstarted
+			p.addChild(child)
+			p.numStarted++
 		}
 		p.subsMu.Unlock()
 
 		if err != nil {
 			return nil, err
 		}
+		if existing != nil {
+			return existing, ErrAlreadyRunning
+		}
 	}
 
-	go func() {
+	if child.task.Owner != nil {
+		child.addToOwnerIndex()
+	}
+	if child.registered {
+		child.addToRegistry()
+	}
+	if p == nil {
+		child.addToRoots()
+	}
 
-		// If there is a parent, wait until child.Close() *or* p.Close()
-		// TODO: merge CloseWhenIdle() into this block?
-		if p != nil {
+	if until, hasOwn := child.initDeadline(p); hasOwn {
+		child.deadlineTimer = child.clock.AfterFunc(until, func() {
+			child.CloseWithError(context.DeadlineExceeded)
+		})
+	}
+
+	if std := child.task.Context; std != nil {
+		go func() {
 			select {
-			case <-p.Closing():
-				child.Close()
+			case <-std.Done():
+				child.CloseWithError(std.Err())
 			case <-child.Closing():
 			}
-		}
+		}()
+	}
+
+	for _, oi := range observers() {
+		oi.OnContextStarted(child)
+	}
+	child.record(Event_Started.String(), "")
+	if p != nil && p.history != nil {
+		p.record("child started", child.ContextPath())
+	}
+	if p != nil && p.task.OnChildStart != nil {
+		p.task.OnChildStart(child)
+	}
 
-		// Wait for child to begin closing phase
-		<-child.Closing()
+	// Setup is complete, so the close sequence can now start whenever Close() is called
+	child.launchClose()
 
-		// Fire callback if given
-		if child.task.OnClosing != nil {
-			child.task.OnClosing()
+	if child.task.OnStart != nil {
+		chaosDelay()
+		started := child.clock.Now()
+		err := child.callOnStart()
+		atomic.StoreInt64(&child.startDuration, int64(child.clock.Now().Sub(started)))
+		atomic.StoreInt32(&child.starting, 0)
+		child.task.OnStart = nil
+		if err != nil {
+			if child.runDone != nil {
+				child.runRelease()
+			}
+
+			// Only restarts (and not the caller's initial StartChild) are retried after an OnStart failure
+			if child.restart != nil && child.restart.restarts == 0 {
+				child.restart.disabled = true
+			}
+			child.closeAs(CloseKind_Failed, err)
+			return nil, err
 		}
+	}
 
-		// Once all child's children are closed, proceed with completion.
-		child.busy.Wait()
+	if child.runDone != nil {
+		go func() {
+			runStarted := child.clock.Now()
+			atomic.StoreInt64(&child.runStartedAt, runStarted.UnixNano())
+			if child.profileLabels {
+				labels := pprof.Labels("cedar_ctx", child.ContextPath(), "cedar_id", strconv.FormatInt(child.id, 10))
+				pprof.Do(context.Background(), labels, func(context.Context) {
+					child.run()
+				})
+			} else {
+				child.run()
+			}
+			atomic.StoreInt64(&child.runDuration, int64(child.clock.Now().Sub(runStarted)))
+			child.task.OnRun = nil
+			child.task.OnRunErr = nil
+			close(child.runDone)
+			child.runRelease()
 
-		closeParent := false
+			// If idleclose is set, try to do so
+			if child.task.IdleClose > 0 {
+				child.CloseWhenIdle(child.task.IdleClose)
+			}
+		}()
+	}
 
-		if p != nil {
+	return child, nil
+}
 
-			p.subsMu.Lock()
-			{
-				// remove the child from its parent
-				N := len(p.subs)
-				for i := 0; i < N; i++ {
-					if p.subs[i] == child {
-						copy(p.subs[i:], p.subs[i+1:N])
-						N--
-						p.subs[N] = nil // show GC some love
-						p.subs = p.subs[:N]
-						break
-					}
-				}
+// runClose executes the close sequence, started by launchClose() once Close() has been called.
+func (child *ctx) runClose() {
+	p := child.getParent()
+	if child.deadlineTimer != nil {
+		child.deadlineTimer.Stop()
+	}
 
-				// If removing the last child and in IdleClose mode, queue the parent to be closed
-				if N == 0 && p.task.IdleClose > 0 {
-					closeParent = true
-				}
-			}
-			p.subsMu.Unlock()
+	for _, oi := range observers() {
+		oi.OnContextClosing(child)
+	}
+	child.closing.runCallbacks()
+	child.record(Event_Closing.String(), errMsg(child.err))
+
+	if deadline := child.closeDeadline(p == nil); deadline > 0 {
+		timer := child.clock.AfterFunc(deadline, func() {
+			child.onCloseDeadline(deadline)
+		})
+		defer timer.Stop()
+	}
+
+	// AbandonRunAfter is measured from Close(), not from once children have closed
+	var abandonTimer Timer
+	if child.runDone != nil && child.task.AbandonRunAfter > 0 {
+		abandonTimer = child.clock.NewTimer(child.task.AbandonRunAfter)
+		defer abandonTimer.Stop()
+	}
+
+	// Fire callback if given
+	if child.task.OnClosing != nil {
+		chaosDelay()
+		child.task.OnClosing()
+	}
+
+	// Once all child's children are closed, proceed with completion.
+	child.closeChildren()
+	child.releaseHolds()
+	if abandonTimer != nil {
+		select {
+		case <-child.runDone:
+		case <-abandonTimer.C():
+			child.Warnf("abandoning OnRun since it did not return within %v of Close()", child.task.AbandonRunAfter)
+			child.runRelease()
 		}
+	}
+
+	// Cleanup children can no longer be started since the below waits on those already started
+	child.subsMu.Lock()
+	child.sealed = true
+	child.subsMu.Unlock()
+	child.busy.Wait()
+
+	closeParent := false
+	drained := false
 
-		// Move to Closed state now that all all that remains is the OnClosed callback and release of the chClosed chan.
-		child.state = Closed
-		if child.task.OnClosed != nil {
-			child.task.OnClosed()
+	if p != nil {
+		reported := child.takeReported()
+		p = child.lockParent()
+		p.removeChild(child)
+		p.appendReported(reported...)
+
+		// A critical child that closes out from under a running parent remains down until a restart replaces it
+		if child.task.Critical && atomic.LoadInt32(&p.state) == Running {
+			atomic.AddInt32(&p.criticalDown, 1)
 		}
-		close(child.chClosed)
 
-		// With child no fully closed, the parent is no longer waiting on this child
-		if p != nil {
-			p.busy.Done()
+		// If removing the last child and in IdleClose mode, queue the parent to be closed
+		if p.numChildren == 0 && p.task.IdleClose > 0 {
+			closeParent = true
 		}
+		drained = p.draining && p.numChildren == 0
+		p.subsMu.Unlock()
+	}
 
-		if closeParent {
-			p.CloseWhenIdle(p.task.IdleClose)
+	if child.task.Owner != nil {
+		child.removeFromOwnerIndex()
+	}
+	if child.registered {
+		child.removeFromRegistry()
+	}
+	if p == nil {
+		child.removeFromRoots()
+	}
+
+	// Move to Closed state now that all all that remains is the OnClosed callback and release of Done().
+	atomic.StoreInt64(&child.closedAt, child.clock.Now().UnixNano())
+	atomic.StoreInt32(&child.state, Closed)
+	if child.task.OnClosed != nil {
+		child.task.OnClosed()
+	}
+	for _, oi := range observers() {
+		oi.OnContextDone(child)
+	}
+	child.record(Event_Closed.String(), child.CloseKind().String())
+	if p != nil && p.history != nil {
+		p.record("child closed", child.ContextPath())
+	}
+	if p != nil && p.task.OnChildClosed != nil {
+		p.task.OnChildClosed(child, child.err)
+	}
+	if p == nil {
+		log.Flush() // so entries logged during shutdown (e.g. to an AsyncSink) are written before Done() releases main()
+	}
+	child.closed.fire()
+	child.closed.runCallbacks()
+
+	// With child no fully closed, the parent is no longer waiting on this child
+	if p != nil {
+		if p.shouldRestart(child) {
+			p.scheduleRestart(child)
 		}
-	}()
+		p.busy.Done()
+	}
 
-	if child.task.OnStart != nil {
-		err := child.task.OnStart(child)
-		child.task.OnStart = nil
+	if closeParent {
+		p.CloseWhenIdle(p.task.IdleClose)
+	}
+	if drained {
+		p.Close()
+	}
+}
+
+// run invokes the Task's OnRun, or OnRunErr with retries as specified by the Task's RunRetry fields.
+func (p *ctx) run() {
+	if p.task.OnRun != nil {
+		err := p.callRecover(func() error {
+			p.task.OnRun(p)
+			return nil
+		})
 		if err != nil {
-			child.Close()
-			return nil, err
+			p.closeAs(CloseKind_Failed, err)
+		} else {
+			p.setCloseKind(CloseKind_Completed)
 		}
+		return
 	}
 
-	if child.task.OnRun != nil {
-		child.busy.Add(1)
-		go func() {
-			child.task.OnRun(child)
-			child.task.OnRun = nil
-			child.busy.Done()
+	policy := retryPolicy{
+		retries: p.task.RunRetries,
+		backoff: newBackoff(p.task.RunRetryBackoff, p.task.RunRetryMaxBackoff),
+		jitter:  p.task.RunRetryJitter,
+		retryable: func(err error) bool {
+			return !IsPermanent(err)
+		},
+	}
+	attempts, abandoned, err := p.retry("OnRun", &policy, p.runAttempt)
+	switch {
+	case err == nil:
+		p.setCloseKind(CloseKind_Completed)
+	case !abandoned:
+		p.Warnf("OnRun failed after %d attempt(s): %v", attempts, err)
+		p.closeAs(CloseKind_Failed, err)
+	}
+}
 
-			// If idleclose is set, try to do so
-			if child.task.IdleClose > 0 {
-				child.CloseWhenIdle(child.task.IdleClose)
-			}
-		}()
+// runAttempt makes a single call to the Task's OnRunErr, bounded by Task.RunRetryTimeout (if set).
+func (p *ctx) runAttempt() error {
+	if p.task.RunRetryTimeout <= 0 {
+		return p.callRecover(func() error {
+			return p.task.OnRunErr(p)
+		})
 	}
 
-	return child, nil
+	attempt, err := p.StartChild(&Task{
+		Label:   "attempt",
+		Timeout: p.task.RunRetryTimeout,
+	})
+	if err != nil {
+		return err
+	}
+	err = attempt.(*ctx).callRecover(func() error {
+		return p.task.OnRunErr(attempt)
+	})
+	timedOut := attempt.Cause() == context.DeadlineExceeded
+	attempt.Close()
+	<-attempt.Done()
+
+	if timedOut && err == nil {
+		err = context.DeadlineExceeded
+	}
+	return err
 }
 
 func (p *ctx) Go(label string, fn func(ctx Context)) (Context, error) {
@@ -278,17 +982,172 @@ func (p *ctx) Go(label string, fn func(ctx Context)) (Context, error) {
 	})
 }
 
+func (p *ctx) GoAfter(delay time.Duration, label string, fn func(ctx Context)) (Context, error) {
+	return p.GoAt(p.clock.Now().Add(delay), label, fn)
+}
+
+func (p *ctx) GoAt(at time.Time, label string, fn func(ctx Context)) (Context, error) {
+	return p.StartChild(scheduledTask(at, label, fn))
+}
+
+// scheduledTask returns the Task started by GoAt().
+func scheduledTask(at time.Time, label string, fn func(ctx Context)) *Task {
+	return &Task{
+		Label:     label,
+		IdleClose: time.Nanosecond,
+		runAt:     at,
+		OnRun: func(child Context) {
+			child.(*ctx).runScheduled(fn)
+		},
+	}
+}
+
+// runScheduled calls fn once Task.runAt is reached unless this Context starts closing first.
+func (p *ctx) runScheduled(fn func(ctx Context)) {
+	timer := p.clock.NewTimer(p.task.runAt.Sub(p.clock.Now()))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+	case <-p.Closing():
+		return
+	}
+	atomic.StoreInt32(&p.scheduled, 0)
+	fn(p)
+}
+
+func (p *ctx) GoErr(label string, fn func(ctx Context) error) (Context, error) {
+	return p.goErr(p.StartChild, label, fn)
+}
+
+// goErr implements GoErr(), where start starts the child (see traceScope).
+func (p *ctx) goErr(start func(task *Task) (Context, error), label string, fn func(ctx Context) error) (Context, error) {
+	p.group.Add(1)
+	child, err := start(&Task{
+		Label:     label,
+		IdleClose: time.Nanosecond,
+		OnRunErr: func(child Context) error {
+			defer p.group.Done()
+			err := child.(*ctx).callRecover(func() error {
+				return fn(child)
+			})
+			if err != nil {
+				child.ReportError(err)
+				p.groupErrOnce.Do(func() {
+					p.groupErr = err
+					p.CloseWithError(err)
+				})
+			}
+			return err
+		},
+	})
+	if err != nil {
+		p.group.Done()
+	}
+	return child, err
+}
+
+func (p *ctx) Wait() error {
+	p.group.Wait()
+	return p.groupErr
+}
+
 func (p *ctx) Closing() <-chan struct{} {
-	return p.chClosing
+	return p.closing.wait()
 }
 
 func (p *ctx) Done() <-chan struct{} {
-	return p.chClosed
+	return p.closed.wait()
+}
+
+func (p *ctx) OnClosing(fn func()) (cancel func()) {
+	return p.closing.onFire(fn)
+}
+
+func (p *ctx) OnDone(fn func()) (cancel func()) {
+	return p.closed.onFire(fn)
+}
+
+// addChild appends the given child to this Context's children.
+// p.subsMu must be held.
+func (p *ctx) addChild(child *ctx) {
+	child.prevSib = p.lastChild
+	if p.lastChild != nil {
+		p.lastChild.nextSib = child
+	} else {
+		p.firstChild = child
+	}
+	p.lastChild = child
+	p.numChildren++
+}
+
+// removeChild removes the given child from this Context's children.
+// p.subsMu must be held.
+func (p *ctx) removeChild(child *ctx) {
+	if child.prevSib != nil {
+		child.prevSib.nextSib = child.nextSib
+	} else {
+		p.firstChild = child.nextSib
+	}
+	if child.nextSib != nil {
+		child.nextSib.prevSib = child.prevSib
+	} else {
+		p.lastChild = child.prevSib
+	}
+	child.prevSib = nil
+	child.nextSib = nil
+	p.numChildren--
+}
+
+// CloseOrder specifies how a closing Context signals its children to close.
+type CloseOrder int32
+
+const (
+	CloseOrder_Parallel CloseOrder = iota // All children are signaled to close at once (default)
+	CloseOrder_LIFO                       // Children are closed in reverse start order, each reaching Done() before the next is signaled
+)
+
+// CloseKind describes why a Context closed.
+//
+// The first of the following to occur determines a Context's CloseKind:
+//   - Complete() is called or OnRun returns => CloseKind_Completed
+//   - OnStart fails (or OnRunErr fails its final attempt) => CloseKind_Failed
+//   - Close() is called (or the parent closes) => CloseKind_Cancelled
+//   - IdleClose or CloseWhenIdle() fires    => CloseKind_Idle
+//
+// So a Context whose OnRun returned normally reports CloseKind_Completed even though it is later closed when idle,
+// but a Context closed while OnRun is still executing reports CloseKind_Cancelled.
+type CloseKind int32
+
+const (
+	CloseKind_Open      CloseKind = iota // Close() has not been called
+	CloseKind_Completed                  // Work completed successfully
+	CloseKind_Cancelled                  // Closed before work completed
+	CloseKind_Idle                       // Closed when idle, having never run or completed work
+	CloseKind_Failed                     // OnStart or OnRunErr (on its final attempt) returned an error
+)
+
+func (kind CloseKind) String() string {
+	switch kind {
+	case CloseKind_Open:
+		return "open"
+	case CloseKind_Completed:
+		return "completed"
+	case CloseKind_Cancelled:
+		return "cancelled"
+	case CloseKind_Idle:
+		return "idle"
+	case CloseKind_Failed:
+		return "failed"
+	}
+	return "unknown"
 }
 
+// Context states (see Context.State), where a Context advances through Starting (if it has an OnStart), Running, Closing, and Closed.
 const (
 	Unstarted int32 = iota
 	Running
 	Closing
 	Closed
+	Starting // OnStart is running
 )
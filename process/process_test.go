@@ -1,13 +1,28 @@
 package process_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/arcspace/go-cedar/log"
 	"github.com/arcspace/go-cedar/process"
+	"github.com/arcspace/go-cedar/process/ptest"
 	"github.com/arcspace/go-cedar/testutils"
 )
 
@@ -135,6 +150,2378 @@ func Test6(t *testing.T) {
 	})
 }
 
+func TestRunRetries(t *testing.T) {
+	t.Run("retries OnRunErr until success", func(t *testing.T) {
+		attempts := 0
+		p, err := process.Start(&process.Task{
+			Label:           "retrier",
+			IdleClose:       time.Nanosecond,
+			RunRetries:      5,
+			RunRetryBackoff: 10 * time.Millisecond,
+			RunRetryJitter:  0.5,
+			OnRunErr: func(ctx process.Context) error {
+				attempts++
+				if attempts < 3 {
+					return errors.New("transient")
+				}
+				return nil
+			},
+		})
+		require.NoError(t, err)
+		require.Eventually(t, func() bool { return isDone(t, p.Done()) }, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("closes after the final failed attempt", func(t *testing.T) {
+		attempts := 0
+		p, err := process.Start(&process.Task{
+			Label:      "retrier",
+			RunRetries: 2,
+			OnRunErr: func(ctx process.Context) error {
+				attempts++
+				return errors.New("permanent")
+			},
+		})
+		require.NoError(t, err)
+		require.Eventually(t, func() bool { return isDone(t, p.Done()) }, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("times out each attempt", func(t *testing.T) {
+		var attempts int32
+		p, err := process.Start(&process.Task{
+			Label:           "retrier",
+			IdleClose:       time.Nanosecond,
+			RunRetries:      3,
+			RunRetryBackoff: time.Millisecond,
+			RunRetryTimeout: 20 * time.Millisecond,
+			OnRunErr: func(ctx process.Context) error {
+				if atomic.AddInt32(&attempts, 1) == 1 {
+					<-ctx.Closing() // hangs until the attempt times out
+					return ctx.Err()
+				}
+				require.Equal(t, "attempt", ctx.Label())
+				return nil
+			},
+		})
+		require.NoError(t, err)
+		select {
+		case <-p.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("attempts did not time out")
+		}
+		require.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+		require.Equal(t, process.CloseKind_Completed, p.CloseKind())
+	})
+
+	t.Run("fails with DeadlineExceeded once out of retries", func(t *testing.T) {
+		p, err := process.Start(&process.Task{
+			Label:           "retrier",
+			RunRetryTimeout: time.Millisecond,
+			OnRunErr: func(ctx process.Context) error {
+				<-ctx.Closing()
+				return nil
+			},
+		})
+		require.NoError(t, err)
+		<-p.Done()
+		require.Equal(t, process.CloseKind_Failed, p.CloseKind())
+		require.ErrorIs(t, p.Cause(), context.DeadlineExceeded)
+	})
+}
+
+func TestOwner(t *testing.T) {
+	t.Run("close by owner", func(t *testing.T) {
+		type tenant struct{ name string }
+
+		p, _ := process.Start(&process.Task{Label: "root"})
+		defer p.Close()
+
+		a1, _ := p.StartChild(&process.Task{Label: "a1", Owner: tenant{"a"}})
+		a2, _ := p.StartChild(&process.Task{Label: "a2", Owner: tenant{"a"}})
+		b1, _ := p.StartChild(&process.Task{Label: "b1", Owner: tenant{"b"}})
+		require.Equal(t, tenant{"a"}, a1.Owner())
+
+		n := 0
+		process.ForEachByOwner(tenant{"a"}, func(ctx process.Context) { n++ })
+		require.Equal(t, 2, n)
+
+		require.Equal(t, 2, process.CloseByOwner(tenant{"a"}))
+		require.Eventually(t, func() bool { return isDone(t, a1.Done()) && isDone(t, a2.Done()) }, 5*time.Second, 10*time.Millisecond)
+		requireDone(t, b1.Done(), false)
+		require.Equal(t, 0, process.CloseByOwner(tenant{"a"}))
+
+		_, err := p.StartChild(&process.Task{Owner: []string{"x"}})
+		require.ErrorIs(t, err, process.ErrBadOwner)
+
+		// A comparable type holding an uncomparable value is rejected rather than panicking
+		type anyOwner struct{ id any }
+		_, err = p.StartChild(&process.Task{Owner: anyOwner{[]string{"x"}}})
+		require.ErrorIs(t, err, process.ErrBadOwner)
+		require.Equal(t, 0, process.CloseByOwner(anyOwner{[]string{"x"}}))
+		process.ForEachByOwner(anyOwner{map[string]int{}}, func(ctx process.Context) { t.Fatal("unexpected owner match") })
+		_, err = p.StartChild(&process.Task{Owner: anyOwner{"x"}})
+		require.NoError(t, err)
+	})
+
+	t.Run("counts only contexts it closed", func(t *testing.T) {
+		p, _ := process.Start(&process.Task{Label: "root"})
+		defer p.Close()
+
+		release := make(chan struct{})
+		defer close(release)
+		draining, _ := p.StartChild(&process.Task{
+			Label:     "draining",
+			Owner:     "tenant",
+			OnClosing: func() { <-release },
+		})
+		p.StartChild(&process.Task{Label: "open", Owner: "tenant"})
+
+		// A Context that is already closing remains indexed until it's closed but isn't counted
+		draining.Close()
+		require.Equal(t, 1, process.CloseByOwner("tenant"))
+		require.Equal(t, 0, process.CloseByOwner("tenant"))
+	})
+}
+
+func TestHoldIdle(t *testing.T) {
+	t.Run("hold defers idle close", func(t *testing.T) {
+		p, _ := process.Start(&process.Task{
+			Label:     "root",
+			IdleClose: time.Nanosecond,
+		})
+
+		release := p.HoldIdle()
+		spawnN(p, 1, 10*time.Millisecond)
+
+		time.Sleep(200 * time.Millisecond)
+		requireDone(t, p.Done(), false)
+
+		release()
+		release()
+		require.Eventually(t, func() bool { return isDone(t, p.Done()) }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("close releases holds", func(t *testing.T) {
+		p, _ := process.Start(&process.Task{Label: "root"})
+		release := p.HoldIdle()
+		p.Close()
+		require.Eventually(t, func() bool { return isDone(t, p.Done()) }, 5*time.Second, 10*time.Millisecond)
+		release()
+	})
+}
+
+func TestTraceID(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+	require.Equal(t, "", p.TraceID())
+
+	p.SetTraceID("req-1")
+	child, _ := p.StartChild(&process.Task{Label: "child"})
+	grandchild, _ := child.StartChild(&process.Task{Label: "grandchild"})
+	require.Equal(t, "req-1", grandchild.TraceID())
+	require.Equal(t, "[grandchild trace=req-1] ", grandchild.GetLogPrefix())
+
+	child.SetTraceID("req-2")
+	require.Equal(t, "req-1", grandchild.TraceID())
+	require.Equal(t, "req-1", p.TraceID())
+
+	// Trace IDs pass through std contexts in both directions
+	stdCtx, cancel := context.WithCancel(grandchild)
+	defer cancel()
+	require.Equal(t, "req-1", process.TraceIDFrom(stdCtx))
+
+	handler, _ := process.StartWithContext(process.ContextWithTraceID(context.Background(), "req-3"), &process.Task{Label: "handler"})
+	defer handler.Close()
+	sub, _ := handler.StartChild(&process.Task{Label: "sub"})
+	require.Equal(t, "req-3", sub.TraceID())
+	require.Equal(t, "[sub trace=req-3] ", sub.GetLogPrefix())
+
+	// WithTraceID applies only to children started via it, so a shared parent can serve many requests
+	server, _ := process.Start(&process.Task{Label: "server"})
+	defer server.Close()
+	reqA, _ := server.WithTraceID("req-A").StartChild(&process.Task{Label: "reqA"})
+	reqB, _ := server.WithTraceID("req-B").Go("reqB", func(ctx process.Context) {})
+	explicit, _ := server.WithTraceID("req-C").StartChild(&process.Task{Label: "explicit", TraceID: "req-D"})
+	require.Equal(t, "req-A", reqA.TraceID())
+	require.Equal(t, "req-B", reqB.TraceID())
+	require.Equal(t, "req-D", explicit.TraceID())
+	require.Equal(t, "", server.TraceID())
+	require.Equal(t, "[reqA trace=req-A] ", reqA.GetLogPrefix())
+
+	// Trace IDs can be set while children are starting and logging
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if i == 0 {
+					server.SetTraceID(fmt.Sprintf("req-%d", j))
+				} else {
+					child, _ := server.StartChild(&process.Task{Label: "worker"})
+					child.Infof(2, "worker %d", j)
+					_ = process.TraceIDFrom(child)
+					child.Close()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestAbandonRun(t *testing.T) {
+	stuck := make(chan struct{})
+	defer close(stuck)
+
+	p, _ := process.Start(&process.Task{
+		Label:           "stubborn",
+		AbandonRunAfter: 100 * time.Millisecond,
+		OnRun: func(ctx process.Context) {
+			<-stuck
+		},
+	})
+	p.Close()
+	requireDone(t, p.Done(), false)
+	require.Eventually(t, func() bool { return isDone(t, p.Done()) }, 5*time.Second, 10*time.Millisecond)
+
+	t.Run("measured from Close", func(t *testing.T) {
+		clock := ptest.NewClock(time.Now())
+		root, _ := process.Start(&process.Task{Label: "root"})
+		defer root.Close()
+
+		p, _ := root.StartChild(&process.Task{
+			Label:           "stubborn",
+			Clock:           clock,
+			CloseOrder:      process.CloseOrder_LIFO, // so each child is waited on in turn
+			AbandonRunAfter: time.Minute,
+			OnRun: func(ctx process.Context) {
+				<-stuck
+			},
+		})
+		release := make(chan struct{})
+		p.StartChild(&process.Task{
+			Label:     "slow",
+			OnClosing: func() { <-release },
+		})
+
+		// The abandon window elapses while the slow child is still closing, so Done() follows as soon as it closes
+		p.Close()
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+		requireDone(t, p.Done(), false)
+		close(release)
+		select {
+		case <-p.Done():
+		case <-time.After(time.Second):
+			t.Fatal("OnRun was not abandoned once the slow child closed")
+		}
+	})
+}
+
+func TestHealth(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	db, _ := p.StartChild(&process.Task{Label: "db", Critical: true})
+	cache, _ := p.StartChild(&process.Task{Label: "cache"})
+	worker, _ := cache.StartChild(&process.Task{Label: "worker"})
+	require.Equal(t, process.Healthy, p.Health())
+
+	worker.SetHealthy(false)
+	require.Equal(t, process.Degraded, p.Health())
+	require.Equal(t, process.Degraded, cache.Health())
+
+	worker.SetHealthy(true)
+	db.SetHealthy(false)
+	require.Equal(t, process.Unhealthy, p.Health())
+
+	db.SetHealthy(true)
+	require.Equal(t, process.Healthy, p.Health())
+
+	// A critical child stays down once fully closed
+	db.Close()
+	<-db.Done()
+	require.Equal(t, process.Unhealthy, p.Health())
+
+	// ... unless a restart replaces it
+	other, _ := process.Start(&process.Task{Label: "other"})
+	defer other.Close()
+	var runs int32
+	_, err := other.StartChild(&process.Task{
+		Label:          "flaky",
+		Critical:       true,
+		RestartPolicy:  process.RestartOnFailure,
+		RestartBackoff: time.Millisecond,
+		OnRunErr: func(ctx process.Context) error {
+			if atomic.AddInt32(&runs, 1) == 1 {
+				return errors.New("crashed")
+			}
+			<-ctx.Closing()
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&runs) == 2 && other.Health() == process.Healthy
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestCloseKind(t *testing.T) {
+	awaitDone := func(ctx process.Context) {
+		require.Eventually(t, func() bool { return isDone(t, ctx.Done()) }, 5*time.Second, 10*time.Millisecond)
+	}
+
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+	require.Equal(t, process.CloseKind_Open, p.CloseKind())
+
+	job, _ := p.Go("job", func(ctx process.Context) {})
+	awaitDone(job)
+	require.Equal(t, process.CloseKind_Completed, job.CloseKind())
+
+	explicit, _ := p.Go("explicit", func(ctx process.Context) {
+		ctx.Complete()
+		<-ctx.Closing()
+	})
+	awaitDone(explicit)
+	require.Equal(t, process.CloseKind_Completed, explicit.CloseKind())
+
+	cancelled, _ := p.Go("cancelled", func(ctx process.Context) {
+		<-ctx.Closing()
+	})
+	cancelled.Close()
+	awaitDone(cancelled)
+	require.Equal(t, process.CloseKind_Cancelled, cancelled.CloseKind())
+
+	idler, _ := p.StartChild(&process.Task{Label: "idler"})
+	idler.CloseWhenIdle(time.Nanosecond)
+	awaitDone(idler)
+	require.Equal(t, process.CloseKind_Idle, idler.CloseKind())
+}
+
+func TestRestartPolicy(t *testing.T) {
+	t.Run("restarts up to MaxRestarts", func(t *testing.T) {
+		p, _ := process.Start(&process.Task{
+			Label:     "supervisor",
+			IdleClose: time.Nanosecond,
+		})
+
+		var runs int32
+		_, err := p.StartChild(&process.Task{
+			Label:          "worker",
+			IdleClose:      time.Nanosecond,
+			RestartPolicy:  process.RestartAlways,
+			MaxRestarts:    3,
+			RestartBackoff: 10 * time.Millisecond,
+			OnRun: func(ctx process.Context) {
+				atomic.AddInt32(&runs, 1)
+			},
+		})
+		require.NoError(t, err)
+		require.Eventually(t, func() bool { return isDone(t, p.Done()) }, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, int32(4), atomic.LoadInt32(&runs))
+	})
+
+	t.Run("explicit close is not restarted", func(t *testing.T) {
+		p, _ := process.Start(&process.Task{Label: "supervisor"})
+		defer p.Close()
+
+		var runs int32
+		child, _ := p.StartChild(&process.Task{
+			Label:         "worker",
+			RestartPolicy: process.RestartOnFailure,
+			OnRunErr: func(ctx process.Context) error {
+				atomic.AddInt32(&runs, 1)
+				<-ctx.Closing()
+				return nil
+			},
+		})
+		child.Close()
+		require.Eventually(t, func() bool { return isDone(t, child.Done()) }, 5*time.Second, 10*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		require.Equal(t, int32(1), atomic.LoadInt32(&runs))
+		require.Empty(t, p.GetChildren(nil))
+	})
+}
+
+func TestCloseWithError(t *testing.T) {
+	errShutdown := errors.New("shutting down")
+
+	p, _ := process.Start(&process.Task{Label: "root"})
+	require.Nil(t, p.Cause())
+
+	var childCause error
+	var child process.Context
+	child, _ = p.StartChild(&process.Task{
+		Label: "child",
+		OnClosing: func() {
+			childCause = child.Cause()
+		},
+	})
+
+	p.CloseWithError(errShutdown)
+	p.Close()
+	require.Eventually(t, func() bool { return isDone(t, p.Done()) }, 5*time.Second, 10*time.Millisecond)
+	require.ErrorIs(t, p.Err(), errShutdown)
+	require.ErrorIs(t, p.Cause(), errShutdown)
+	require.ErrorIs(t, child.Err(), errShutdown)
+	require.ErrorIs(t, childCause, errShutdown)
+}
+
+func TestPanicRecovery(t *testing.T) {
+	var recovered any
+	p, _ := process.Start(&process.Task{
+		Label: "panicker",
+		OnPanic: func(ctx process.Context, r any, stack []byte) {
+			recovered = r
+		},
+		OnRun: func(ctx process.Context) {
+			panic("boom")
+		},
+	})
+	require.Eventually(t, func() bool { return isDone(t, p.Done()) }, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, "boom", recovered)
+	require.Equal(t, process.CloseKind_Failed, p.CloseKind())
+
+	var panicErr *process.PanicError
+	require.ErrorAs(t, p.Err(), &panicErr)
+
+	_, err := process.Start(&process.Task{
+		Label: "start panicker",
+		OnStart: func(ctx process.Context) error {
+			panic("boom")
+		},
+	})
+	require.ErrorAs(t, err, &panicErr)
+}
+
+func TestGoErr(t *testing.T) {
+	errFirst := errors.New("first")
+
+	p, _ := process.Start(&process.Task{Label: "group"})
+	p.GoErr("fails", func(ctx process.Context) error {
+		return errFirst
+	})
+	p.GoErr("waits", func(ctx process.Context) error {
+		<-ctx.Closing()
+		return errors.New("second")
+	})
+	p.GoErr("succeeds", func(ctx process.Context) error {
+		return nil
+	})
+
+	require.ErrorIs(t, p.Wait(), errFirst)
+	require.Eventually(t, func() bool { return isDone(t, p.Done()) }, 5*time.Second, 10*time.Millisecond)
+	require.ErrorIs(t, p.Err(), errFirst)
+}
+
+func TestTreeSnapshot(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	a, _ := p.StartChild(&process.Task{Label: "a"})
+	a.StartChild(&process.Task{Label: "a1"})
+	p.StartChild(&process.Task{Label: "b"})
+
+	var labels []string
+	process.Walk(p, func(ctx process.Context, depth int) bool {
+		labels = append(labels, fmt.Sprintf("%d:%s", depth, ctx.Label()))
+		return ctx.Label() != "a"
+	})
+	require.Equal(t, []string{"0:root", "1:a", "1:b"}, labels)
+
+	node := process.TreeSnapshot(p)
+	require.Equal(t, "root", node.Label)
+	require.Equal(t, "running", node.State)
+	require.Len(t, node.Children, 2)
+	require.Equal(t, "a1", node.Children[0].Children[0].Label)
+
+	var text, js strings.Builder
+	process.PrintTree(p, &text)
+	require.Contains(t, text.String(), "        ")
+	require.NoError(t, process.PrintTreeJSON(p, &js))
+	require.Contains(t, js.String(), `"label": "a1"`)
+}
+
+func TestCloseOrderLIFO(t *testing.T) {
+	p, _ := process.Start(&process.Task{
+		Label:      "root",
+		CloseOrder: process.CloseOrder_LIFO,
+	})
+
+	var order []string
+	for _, label := range []string{"db", "cache", "api"} {
+		label := label
+		p.StartChild(&process.Task{
+			Label: label,
+			OnClosed: func() {
+				order = append(order, label)
+			},
+		})
+	}
+
+	p.Close()
+	require.Eventually(t, func() bool { return isDone(t, p.Done()) }, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"api", "cache", "db"}, order)
+}
+
+func TestCloseDeadline(t *testing.T) {
+	stuck := make(chan struct{})
+	escalated := testutils.NewAwaiter()
+
+	var stuckLabels []string
+	p, _ := process.Start(&process.Task{
+		Label:         "root",
+		CloseDeadline: 50 * time.Millisecond,
+		OnCloseDeadline: func(ctx process.Context, stuck []process.Context) {
+			for _, ci := range stuck {
+				stuckLabels = append(stuckLabels, ci.Label())
+			}
+			escalated.ItHappened()
+		},
+	})
+	p.StartChild(&process.Task{Label: "fine"})
+	p.Go("hung", func(ctx process.Context) {
+		<-stuck
+	})
+
+	p.Close()
+	escalated.AwaitOrFail(t, 5*time.Second)
+	require.Equal(t, []string{"root", "hung"}, stuckLabels)
+
+	close(stuck)
+	require.Eventually(t, func() bool { return isDone(t, p.Done()) }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestStartWithContext(t *testing.T) {
+	stdCtx, cancel := context.WithCancel(context.Background())
+	p, err := process.StartWithContext(stdCtx, &process.Task{Label: "embedded"})
+	require.NoError(t, err)
+	child, _ := p.StartChild(&process.Task{Label: "child"})
+
+	requireDone(t, p.Done(), false)
+	cancel()
+	require.Eventually(t, func() bool { return isDone(t, child.Done()) && isDone(t, p.Done()) }, 5*time.Second, 10*time.Millisecond)
+	require.ErrorIs(t, p.Err(), context.Canceled)
+}
+
+func TestTimeout(t *testing.T) {
+	p, _ := process.Start(&process.Task{
+		Label:   "root",
+		Timeout: time.Hour,
+	})
+	defer p.Close()
+
+	child, _ := p.StartChild(&process.Task{
+		Label:   "child",
+		Timeout: 50 * time.Millisecond,
+	})
+	grandchild, _ := child.StartChild(&process.Task{
+		Label:   "grandchild",
+		Timeout: time.Hour,
+	})
+
+	rootDeadline, ok := p.Deadline()
+	require.True(t, ok)
+	childDeadline, _ := child.Deadline()
+	grandchildDeadline, _ := grandchild.Deadline()
+	require.True(t, childDeadline.Before(rootDeadline))
+	require.Equal(t, childDeadline, grandchildDeadline)
+
+	require.Eventually(t, func() bool { return isDone(t, grandchild.Done()) }, 5*time.Second, 10*time.Millisecond)
+	require.ErrorIs(t, grandchild.Err(), context.DeadlineExceeded)
+	requireDone(t, p.Done(), false)
+}
+
+func TestValues(t *testing.T) {
+	type key string
+
+	stdCtx := context.WithValue(context.Background(), key("request"), "req-1")
+	p, _ := process.StartWithContext(stdCtx, &process.Task{
+		Label:  "root",
+		Values: map[any]any{key("db"): "root-db"},
+	})
+	defer p.Close()
+
+	child, _ := p.StartChild(&process.Task{Label: "child"})
+	grandchild, _ := child.StartChild(&process.Task{Label: "grandchild"})
+	require.Equal(t, "root-db", grandchild.Value(key("db")))
+	require.Equal(t, "req-1", grandchild.Value(key("request")))
+	require.Nil(t, grandchild.Value(key("missing")))
+
+	child.SetValue(key("db"), "child-db")
+	require.Equal(t, "child-db", grandchild.Value(key("db")))
+	require.Equal(t, "root-db", p.Value(key("db")))
+}
+
+func TestLogLevel(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	child, _ := p.StartChild(&process.Task{Label: "child"})
+	grandchild, _ := child.StartChild(&process.Task{Label: "grandchild"})
+	require.False(t, grandchild.LogV(5))
+
+	p.SetLogLevel(log.Debug)
+	require.True(t, grandchild.LogV(5))
+
+	child.SetLogLevel(log.Warn)
+	require.False(t, grandchild.LogV(5))
+	require.True(t, p.LogV(5))
+
+	child.SetLogLevel(log.DefaultLevel)
+	require.True(t, grandchild.LogV(5))
+}
+
+func TestIsDescendant(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+	other, _ := process.Start(&process.Task{Label: "root"})
+	defer other.Close()
+
+	shardA, _ := p.StartChild(&process.Task{Label: "shard"})
+	shardB, _ := p.StartChild(&process.Task{Label: "shard"})
+	session, _ := shardA.StartChild(&process.Task{Label: "session"})
+
+	require.True(t, process.IsDescendant(p, p))
+	require.True(t, process.IsDescendant(session, p))
+	require.True(t, process.IsDescendant(session, shardA))
+	require.True(t, process.IsDescendant(session, p.WithTraceID("trace")))
+	require.False(t, process.IsDescendant(p, session))
+	require.False(t, process.IsDescendant(session, shardB))
+	require.False(t, process.IsDescendant(session, other))
+
+	// Containment follows the tree as it is now
+	require.NoError(t, session.MoveTo(shardB))
+	require.True(t, process.IsDescendant(session, shardB))
+	require.False(t, process.IsDescendant(session, shardA))
+}
+
+func TestFindByPath(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	grpc, _ := p.StartChild(&process.Task{Label: "grpc"})
+	server, _ := grpc.StartChild(&process.Task{Label: "server"})
+	session, _ := server.StartChild(&process.Task{Label: "session-42"})
+
+	require.Equal(t, grpc, p.GetChild("grpc"))
+	require.Nil(t, p.GetChild("server"))
+	require.Equal(t, session, process.FindByPath(p, "grpc/server/session-42"))
+	require.Equal(t, server, process.FindByPath(p, "/grpc/server/"))
+	require.Equal(t, p, process.FindByPath(p, ""))
+	require.Nil(t, process.FindByPath(p, "grpc/session-42"))
+
+	session.Close()
+	<-session.Done()
+	require.Nil(t, process.FindByPath(p, "grpc/server/session-42"))
+}
+
+func TestUniqueChild(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	svc, err := p.StartChild(&process.Task{Label: "svc", Unique: true})
+	require.NoError(t, err)
+
+	again, err := p.StartChild(&process.Task{Label: "svc", Unique: true})
+	require.ErrorIs(t, err, process.ErrAlreadyRunning)
+	require.Equal(t, svc, again)
+	require.Equal(t, 1, len(p.GetChildren(nil)))
+
+	svc.Close()
+	<-svc.Done()
+	again, err = p.StartChild(&process.Task{Label: "svc", Unique: true})
+	require.NoError(t, err)
+	require.NotEqual(t, svc, again)
+}
+
+func TestJobPool(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	pool, err := process.StartNewJobPool(p, "jobs", 4)
+	require.NoError(t, err)
+
+	var running, maxRunning, completed int32
+	for i := 0; i < 100; i++ {
+		err := pool.Submit(func(ctx process.Context) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&completed, 1)
+		})
+		require.NoError(t, err)
+	}
+	require.LessOrEqual(t, len(pool.GetChildren(nil)), 4)
+
+	pool.Close()
+	<-pool.Done()
+	require.EqualValues(t, 100, atomic.LoadInt32(&completed))
+	require.LessOrEqual(t, atomic.LoadInt32(&maxRunning), int32(4))
+	require.Equal(t, 0, pool.NumWorkers())
+	require.ErrorIs(t, pool.Submit(func(ctx process.Context) {}), process.ErrClosed)
+}
+
+func TestGoPeriodic(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	var runs int32
+	ticker, err := p.GoPeriodic("ticker", 5*time.Millisecond, func(ctx process.Context) {
+		atomic.AddInt32(&runs, 1)
+	})
+	require.NoError(t, err)
+
+	time.Sleep(60 * time.Millisecond)
+	ticker.Close()
+	<-ticker.Done()
+	n := atomic.LoadInt32(&runs)
+	require.GreaterOrEqual(t, n, int32(3))
+
+	time.Sleep(20 * time.Millisecond)
+	require.Equal(t, n, atomic.LoadInt32(&runs))
+}
+
+// untilSchedule runs at a fixed interval until the given time.
+type untilSchedule struct {
+	every time.Duration
+	until time.Time
+}
+
+func (sched untilSchedule) Next(after time.Time) time.Time {
+	if next := after.Add(sched.every); next.Before(sched.until) {
+		return next
+	}
+	return time.Time{}
+}
+
+func TestGoScheduledEnds(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	var runs int32
+	sched := untilSchedule{every: 5 * time.Millisecond, until: time.Now().Add(30 * time.Millisecond)}
+	finite, err := process.GoScheduled(p, "finite", sched, process.Overlap_Concurrent, func(ctx process.Context) {
+		atomic.AddInt32(&runs, 1)
+	})
+	require.NoError(t, err)
+
+	select {
+	case <-finite.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context did not close once its schedule ended")
+	}
+	require.Equal(t, process.CloseKind_Completed, finite.CloseKind())
+	require.Greater(t, atomic.LoadInt32(&runs), int32(0))
+
+	never, err := p.GoPeriodic("never", 0, func(ctx process.Context) {
+		t.Error("fn called without a schedule")
+	})
+	require.NoError(t, err)
+	select {
+	case <-never.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context with no next run time did not close")
+	}
+}
+
+func TestParseCron(t *testing.T) {
+	at := func(s string) time.Time {
+		t, _ := time.Parse("2006-01-02 15:04", s)
+		return t
+	}
+
+	tests := []struct {
+		expr, after, next string
+	}{
+		{"*/15 * * * *", "2024-03-10 10:07", "2024-03-10 10:15"},
+		{"0 9 * * 1-5", "2024-03-08 09:00", "2024-03-11 09:00"}, // Friday -> Monday
+		{"30 2 1 * *", "2024-01-15 00:00", "2024-02-01 02:30"},
+		{"@yearly", "2024-06-01 00:00", "2025-01-01 00:00"},
+		{"0 0 29 2 *", "2024-03-01 00:00", "2028-02-29 00:00"},
+		{"0 12 13 * 5", "2024-03-10 00:00", "2024-03-13 12:00"}, // either the 13th or a Friday
+	}
+	for _, test := range tests {
+		sched, err := process.ParseCron(test.expr)
+		require.NoError(t, err, test.expr)
+		require.Equal(t, at(test.next), sched.Next(at(test.after)), test.expr)
+	}
+
+	for _, bad := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *"} {
+		_, err := process.ParseCron(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestGoAfter(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	ran := make(chan struct{})
+	later, err := p.GoAfter(20*time.Millisecond, "later", func(ctx process.Context) {
+		close(ran)
+	})
+	require.NoError(t, err)
+
+	node := process.TreeSnapshot(p)
+	require.Equal(t, "scheduled", node.Children[0].State)
+	require.NotNil(t, node.Children[0].ScheduledAt)
+	require.False(t, node.Children[0].ScheduledAt.IsZero())
+
+	// Only scheduled Contexts report when they'll run
+	buf, err := json.Marshal(node)
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(string(buf), `"scheduled_at"`))
+
+	<-ran
+	<-later.Done()
+	require.Equal(t, process.CloseKind_Completed, later.CloseKind())
+
+	never, _ := p.GoAt(time.Now().Add(time.Hour), "never", func(ctx process.Context) {
+		t.Error("cancelled fn was called")
+	})
+	never.Close()
+	<-never.Done()
+	require.Equal(t, process.CloseKind_Cancelled, never.CloseKind())
+}
+
+func TestGoValue(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	sum := process.GoValue(p, "sum", func(ctx process.Context) (int, error) {
+		return 1 + 2, nil
+	})
+	val, err := sum.Wait()
+	require.NoError(t, err)
+	require.Equal(t, 3, val)
+
+	errFail := errors.New("fail")
+	fail := process.GoValue(p, "fail", func(ctx process.Context) (string, error) {
+		return "", errFail
+	})
+	<-fail.Done()
+	require.ErrorIs(t, fail.Err(), errFail)
+
+	panicked := process.GoValue(p, "panicked", func(ctx process.Context) (int, error) {
+		panic("boom")
+	})
+	_, err = panicked.Wait()
+	var panicErr *process.PanicError
+	require.ErrorAs(t, err, &panicErr)
+	require.Equal(t, "boom", panicErr.Recovered)
+	<-panicked.Context().Done()
+	require.Equal(t, process.CloseKind_Failed, panicked.Context().CloseKind())
+	require.Equal(t, err, panicked.Context().Cause())
+
+	p.Close()
+	<-p.Done()
+	late := process.GoValue(p, "late", func(ctx process.Context) (int, error) {
+		return 0, nil
+	})
+	_, err = late.Wait()
+	require.Error(t, err)
+	require.Nil(t, late.Context())
+}
+
+func TestChildHooks(t *testing.T) {
+	var mu sync.Mutex
+	live := map[int64]string{}
+	var closedErr error
+
+	p, _ := process.Start(&process.Task{
+		Label: "root",
+		OnChildStart: func(child process.Context) {
+			mu.Lock()
+			live[child.ContextID()] = child.Label()
+			mu.Unlock()
+		},
+		OnChildClosed: func(child process.Context, err error) {
+			mu.Lock()
+			delete(live, child.ContextID())
+			closedErr = err
+			mu.Unlock()
+		},
+	})
+	defer p.Close()
+
+	a, _ := p.StartChild(&process.Task{Label: "a"})
+	b, _ := p.StartChild(&process.Task{Label: "b"})
+	mu.Lock()
+	require.Equal(t, map[int64]string{a.ContextID(): "a", b.ContextID(): "b"}, live)
+	mu.Unlock()
+
+	errBad := errors.New("bad")
+	a.CloseWithError(errBad)
+	<-a.Done()
+	mu.Lock()
+	require.Equal(t, map[int64]string{b.ContextID(): "b"}, live)
+	require.Equal(t, errBad, closedErr)
+	mu.Unlock()
+}
+
+func TestSubscribe(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	events := process.Subscribe(process.EventFilter{
+		Root:  p,
+		Kinds: process.Event_Started | process.Event_Closed | process.Event_Panicked,
+	})
+	defer process.Unsubscribe(events)
+
+	other, _ := process.Start(&process.Task{Label: "other"})
+	other.Close()
+	<-other.Done()
+
+	child, _ := p.StartChild(&process.Task{
+		Label:   "child",
+		OnRun:   func(ctx process.Context) { panic("boom") },
+		OnPanic: func(ctx process.Context, recovered any, stack []byte) {},
+	})
+	<-child.Done()
+
+	var kinds []process.EventKind
+	for len(kinds) < 3 {
+		ev := <-events
+		require.Equal(t, child, ev.Context)
+		kinds = append(kinds, ev.Kind)
+	}
+	require.Equal(t, []process.EventKind{process.Event_Started, process.Event_Panicked, process.Event_Closed}, kinds)
+}
+
+func TestOnSignal(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	stop := process.OnSignal(p, syscall.SIGHUP)
+	defer stop()
+
+	proc, _ := os.FindProcess(os.Getpid())
+	require.NoError(t, proc.Signal(syscall.SIGHUP))
+
+	select {
+	case <-p.Done():
+	case <-time.After(time.Second):
+		t.Fatal("root was not closed by signal")
+	}
+}
+
+func TestOnShutdown(t *testing.T) {
+	forced := make(chan struct{})
+	prevExit := process.ForceExit
+	process.ForceExit = func() { close(forced) }
+	defer func() { process.ForceExit = prevExit }()
+
+	release := make(chan struct{})
+	p, _ := process.Start(&process.Task{Label: "root"})
+	p.StartChild(&process.Task{
+		Label:     "stuck",
+		OnClosing: func() { <-release },
+	})
+
+	trigger := process.NewManualTrigger()
+	stop := process.OnShutdown(p, trigger)
+	defer stop()
+
+	trigger.Trigger("test shutdown")
+	<-p.Closing()
+	requireDone(t, forced, false)
+
+	trigger.Trigger("test shutdown")
+	select {
+	case <-forced:
+	case <-time.After(time.Second):
+		t.Fatal("repeated trigger did not force exit")
+	}
+	close(release)
+	<-p.Done()
+}
+
+func TestStartCmd(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	failed, err := process.StartCmd(p, "exit3", exec.Command("sh", "-c", "echo hi; exit 3"), &process.CmdOpts{LogOutput: true})
+	require.NoError(t, err)
+	<-failed.Done()
+	require.Equal(t, process.CloseKind_Failed, failed.CloseKind())
+	var exitErr *exec.ExitError
+	require.True(t, errors.As(failed.Cause(), &exitErr))
+	require.Equal(t, 3, exitErr.ExitCode())
+
+	exited, err := process.StartCmd(p, "exit0", exec.Command("sh", "-c", "exit 0"), nil)
+	require.NoError(t, err)
+	select {
+	case <-exited.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context did not close after its subprocess exited")
+	}
+	require.Equal(t, process.CloseKind_Completed, exited.CloseKind())
+	require.Equal(t, context.Canceled, exited.Cause())
+
+	start := time.Now()
+	sleeper, err := process.StartCmd(p, "sleeper", exec.Command("sleep", "10"), nil)
+	require.NoError(t, err)
+	sleeper.Close()
+	<-sleeper.Done()
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.Equal(t, process.CloseKind_Cancelled, sleeper.CloseKind())
+
+	_, err = process.StartCmd(p, "missing", exec.Command("/nonexistent/cmd"), nil)
+	require.Error(t, err)
+}
+
+func TestHTTPServer(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	inHandler := make(chan struct{})
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(inHandler)
+			time.Sleep(20 * time.Millisecond)
+			w.Write([]byte("ok"))
+		}),
+	}
+	server, err := process.HTTPServer(p, "http", srv, &process.HTTPServerOpts{Listener: ln})
+	require.NoError(t, err)
+
+	chStatus := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			chStatus <- 0
+			return
+		}
+		resp.Body.Close()
+		chStatus <- resp.StatusCode
+	}()
+
+	// The in-flight request completes even though the server is closed while it is being handled
+	<-inHandler
+	server.Close()
+	require.Equal(t, http.StatusOK, <-chStatus)
+	<-server.Done()
+	require.Equal(t, process.CloseKind_Cancelled, server.CloseKind())
+
+	_, err = process.HTTPServer(p, "bad", &http.Server{Addr: "127.0.0.1:-1"}, nil)
+	require.Error(t, err)
+}
+
+func TestStartRetry(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	errTransient := errors.New("transient")
+	attempts := 0
+	child, err := p.StartChild(&process.Task{
+		Label: "flaky",
+		StartRetry: process.StartRetryPolicy{
+			Retries: 3,
+			Backoff: time.Millisecond,
+		},
+		OnStart: func(ctx process.Context) error {
+			if attempts++; attempts < 3 {
+				return errTransient
+			}
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, attempts)
+	require.Equal(t, process.Running, child.State())
+
+	errFatal := errors.New("fatal")
+	attempts = 0
+	_, err = p.StartChild(&process.Task{
+		Label: "permanent",
+		StartRetry: process.StartRetryPolicy{
+			Retries: 3,
+			Backoff: time.Millisecond,
+		},
+		OnStart: func(ctx process.Context) error {
+			attempts++
+			return process.Permanent(errFatal)
+		},
+	})
+	require.ErrorIs(t, err, errFatal)
+	require.True(t, process.IsPermanent(err))
+	require.Equal(t, 1, attempts)
+
+	attempts = 0
+	_, err = p.StartChild(&process.Task{
+		Label: "not-retryable",
+		StartRetry: process.StartRetryPolicy{
+			Retries:   3,
+			Retryable: func(err error) bool { return err != errTransient },
+		},
+		OnStart: func(ctx process.Context) error {
+			attempts++
+			return errTransient
+		},
+	})
+	require.ErrorIs(t, err, errTransient)
+	require.Equal(t, 1, attempts)
+}
+
+func TestWatchdog(t *testing.T) {
+	chLeaks := make(chan process.Leak, 10)
+	stop := process.StartWatchdog(process.WatchdogOpts{
+		Interval:        5 * time.Millisecond,
+		ClosingTimeout:  20 * time.Millisecond,
+		IdleRootTimeout: 20 * time.Millisecond,
+		OnLeaks: func(leaks []process.Leak) {
+			for _, leak := range leaks {
+				chLeaks <- leak
+			}
+		},
+	})
+	defer stop()
+
+	release := make(chan struct{})
+	p, _ := process.Start(&process.Task{Label: "root"})
+	stuck, _ := p.StartChild(&process.Task{
+		Label:     "stuck",
+		OnClosing: func() { <-release },
+	})
+	stuck.Close()
+
+	// Ignore leftovers from other tests
+	nextLeak := func() process.Leak {
+		for {
+			leak := <-chLeaks
+			if leak.Context == p || leak.Context == stuck {
+				return leak
+			}
+		}
+	}
+
+	leak := nextLeak()
+	require.Equal(t, stuck, leak.Context)
+	require.Equal(t, "closing", leak.Reason)
+	require.GreaterOrEqual(t, leak.Age, 20*time.Millisecond)
+	require.Contains(t, leak.StartStack, "TestWatchdog")
+	close(release)
+	<-stuck.Done()
+
+	leak = nextLeak()
+	require.Equal(t, p, leak.Context)
+	require.Equal(t, "idle root", leak.Reason)
+	p.Close()
+	<-p.Done()
+}
+
+func TestWatchdogStartStack(t *testing.T) {
+	stop := process.StartWatchdog(process.WatchdogOpts{
+		Interval: time.Hour,
+		OnLeaks:  func(leaks []process.Leak) {},
+	})
+	defer stop()
+
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	// Stacks begin with the caller, however the Context was started
+	requireStartedHere := func(ctx process.Context) {
+		t.Helper()
+		stack := process.StartStack(ctx)
+		first := strings.SplitN(stack, "\n", 2)[0]
+		require.Contains(t, first, "TestWatchdogStartStack", stack)
+	}
+	release := make(chan struct{})
+	defer close(release)
+	wait := func(ctx process.Context) { <-release }
+
+	child, _ := p.StartChild(&process.Task{Label: "child"})
+	requireStartedHere(child)
+	goer, _ := p.Go("goer", wait)
+	requireStartedHere(goer)
+	later, _ := p.GoAfter(time.Hour, "later", wait)
+	requireStartedHere(later)
+	traced, _ := p.WithTraceID("req-1").Go("traced", wait)
+	requireStartedHere(traced)
+
+	// A restarted Context reports the stack that started its first incarnation
+	var runs int32
+	incarnations := make(chan process.Context, 2)
+	_, err := p.StartChild(&process.Task{
+		Label:          "flaky",
+		RestartPolicy:  process.RestartOnFailure,
+		RestartBackoff: time.Millisecond,
+		OnRunErr: func(ctx process.Context) error {
+			incarnations <- ctx
+			if atomic.AddInt32(&runs, 1) == 1 {
+				return errors.New("crashed")
+			}
+			<-ctx.Closing()
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	first, second := <-incarnations, <-incarnations
+	require.NotEqual(t, first, second)
+	requireStartedHere(second)
+	require.Equal(t, process.StartStack(first), process.StartStack(second))
+}
+
+func TestTimers(t *testing.T) {
+	clock := ptest.NewClock(time.Time{})
+	p, _ := process.Start(&process.Task{Label: "root", Clock: clock})
+	defer p.Close()
+
+	slept := make(chan bool)
+	go func() { slept <- p.Sleep(time.Minute) }()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	require.True(t, <-slept)
+
+	ticks := p.Tick(time.Second)
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	<-ticks
+
+	fired := make(chan struct{})
+	p.AfterFunc(time.Hour, func() { close(fired) })
+	cancelled := p.AfterFunc(time.Hour, func() { t.Error("stopped fn was called") })
+	require.True(t, cancelled())
+	require.False(t, cancelled())
+	clock.BlockUntil(2) // the Tick and remaining AfterFunc timers
+	clock.Advance(time.Hour)
+	<-fired
+
+	go func() { slept <- p.Sleep(time.Minute) }()
+	clock.BlockUntil(2)
+	p.Close()
+	require.False(t, <-slept)
+	for range ticks {
+	}
+}
+
+func TestDrain(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	server, _ := p.StartChild(&process.Task{Label: "server"})
+	session, _ := server.StartChild(&process.Task{Label: "session"})
+
+	server.Drain()
+	_, err := server.StartChild(&process.Task{Label: "late"})
+	require.ErrorIs(t, err, process.ErrClosing)
+	require.Equal(t, "draining", process.TreeSnapshot(p).Children[0].State)
+	requireDone(t, server.Closing(), false)
+
+	session.Close()
+	<-server.Done()
+	require.Equal(t, process.Running, p.State())
+
+	idle, _ := p.StartChild(&process.Task{Label: "idle"})
+	idle.Drain()
+	<-idle.Done()
+}
+
+func TestDetached(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	session, _ := p.StartChild(&process.Task{Label: "session"})
+	session.SetValue("user", "alice")
+	flush, err := session.StartChild(&process.Task{
+		Label:    "flush",
+		Detached: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, len(session.GetChildren(nil)))
+	require.Equal(t, 2, len(p.GetChildren(nil)))
+
+	session.Close()
+	<-session.Done()
+	requireDone(t, flush.Closing(), false)
+	require.Equal(t, "alice", flush.Value("user"))
+
+	flush.Close()
+	<-flush.Done()
+}
+
+func TestMoveTo(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	shardA, _ := p.StartChild(&process.Task{Label: "shard-a"})
+	shardB, _ := p.StartChild(&process.Task{Label: "shard-b"})
+	session, _ := shardA.StartChild(&process.Task{Label: "session"})
+	conn, _ := session.StartChild(&process.Task{Label: "conn"})
+
+	require.ErrorIs(t, p.MoveTo(shardB), process.ErrBadParent)
+	require.ErrorIs(t, session.MoveTo(conn), process.ErrBadParent)
+
+	require.NoError(t, session.MoveTo(shardB))
+	require.Nil(t, shardA.GetChild("session"))
+	require.Equal(t, session, shardB.GetChild("session"))
+	require.Equal(t, conn, process.FindByPath(p, "shard-b/session/conn"))
+
+	shardA.Close()
+	<-shardA.Done()
+	requireDone(t, session.Closing(), false)
+
+	shardB.Close()
+	<-shardB.Done()
+	requireDone(t, conn.Done(), true)
+	require.ErrorIs(t, session.MoveTo(p), process.ErrClosing)
+}
+
+func TestJoin(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	<-process.Join()
+
+	a, _ := p.StartChild(&process.Task{Label: "worker"})
+	b, _ := p.StartChild(&process.Task{Label: "worker"})
+	other, _ := p.StartChild(&process.Task{Label: "other"})
+
+	chJoined := process.Join(a, nil, b)
+	a.Close()
+	requireDone(t, chJoined, false)
+
+	chWaited := make(chan struct{})
+	go func() {
+		p.WaitChildren("worker")
+		close(chWaited)
+	}()
+	b.Close()
+	<-chJoined
+	<-chWaited
+	requireDone(t, other.Closing(), false)
+
+	go other.Close()
+	p.WaitChildren()
+	require.Equal(t, 0, len(p.GetChildren(nil)))
+}
+
+func TestTouch(t *testing.T) {
+	clock := ptest.NewClock(time.Now())
+	p, _ := process.Start(&process.Task{Label: "root", Clock: clock})
+	defer p.Close()
+
+	session, _ := p.StartChild(&process.Task{Label: "session"})
+	session.CloseWhenIdle(time.Minute)
+	clock.BlockUntil(1)
+
+	clock.Advance(30 * time.Second)
+	session.Touch()
+	clock.Advance(30 * time.Second)
+	clock.BlockUntil(1)
+	requireDone(t, session.Closing(), false)
+
+	clock.Advance(time.Minute)
+	<-session.Done()
+	require.Equal(t, process.CloseKind_Idle, session.CloseKind())
+}
+
+func TestIdleCloseMin(t *testing.T) {
+	clock := ptest.NewClock(time.Now())
+	p, _ := process.Start(&process.Task{Label: "root", Clock: clock})
+	defer p.Close()
+
+	handshaking := int32(1)
+	var idleCalls int32
+	session, _ := p.StartChild(&process.Task{
+		Label:        "session",
+		IdleCloseMin: 5 * time.Minute,
+		OnIdle: func(ctx process.Context) bool {
+			atomic.AddInt32(&idleCalls, 1)
+			return atomic.LoadInt32(&handshaking) != 0
+		},
+	})
+	session.CloseWhenIdle(time.Minute)
+	clock.BlockUntil(1)
+
+	// Idle past the delay but younger than IdleCloseMin
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	requireDone(t, session.Closing(), false)
+	require.Equal(t, int32(0), atomic.LoadInt32(&idleCalls))
+
+	// OnIdle vetoes the close, restarting the delay
+	clock.Advance(4 * time.Minute)
+	clock.BlockUntil(1)
+	requireDone(t, session.Closing(), false)
+	require.Equal(t, int32(1), atomic.LoadInt32(&idleCalls))
+
+	atomic.StoreInt32(&handshaking, 0)
+	clock.Advance(time.Minute)
+	<-session.Done()
+	require.Equal(t, process.CloseKind_Idle, session.CloseKind())
+	require.Equal(t, int32(2), atomic.LoadInt32(&idleCalls))
+}
+
+func TestContextPath(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	renders := 0
+	id := stringerFunc(func() string {
+		renders++
+		return "A"
+	})
+	session, _ := p.StartChild(&process.Task{Label: "session-%v", LabelArgs: []any{id}})
+	reader, _ := session.StartChild(&process.Task{Label: "reader"})
+	require.Equal(t, 0, renders)
+
+	require.Equal(t, "session-A", session.Label())
+	session.Info(2, "started")
+	require.Equal(t, "session-A", session.GetLogLabel())
+	require.Equal(t, 1, renders)
+
+	want := fmt.Sprintf("root#%d/session-A#%d/reader#%d", p.ContextID(), session.ContextID(), reader.ContextID())
+	require.Equal(t, want, reader.ContextPath())
+	require.Equal(t, reader, process.FindByPath(p, "session-A/reader"))
+}
+
+type stringerFunc func() string
+
+func (fn stringerFunc) String() string {
+	return fn()
+}
+
+func TestProfileLabels(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root", ProfileLabels: true})
+	defer p.Close()
+
+	session, _ := p.StartChild(&process.Task{Label: "session"})
+	running := make(chan struct{})
+	reader, _ := process.Go(session, "reader", func(ctx process.Context) {
+		close(running)
+		<-ctx.Closing()
+	})
+	<-running
+
+	profile := &strings.Builder{}
+	pprof.Lookup("goroutine").WriteTo(profile, 1)
+	require.Contains(t, profile.String(), fmt.Sprintf(`"cedar_ctx":"%s"`, reader.ContextPath()))
+	require.Contains(t, profile.String(), fmt.Sprintf(`"cedar_id":"%d"`, reader.ContextID()))
+}
+
+func TestSequence(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	var mu sync.Mutex
+	var events []string
+	record := func(event string) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}
+	service := func(label string) *process.Task {
+		return &process.Task{
+			Label: label,
+			OnStart: func(ctx process.Context) error {
+				record("start " + label)
+				return nil
+			},
+			OnClosed: func() {
+				record("closed " + label)
+			},
+		}
+	}
+
+	seq, err := process.Sequence(p, "service", service("db"), service("cache"), service("api"))
+	require.NoError(t, err)
+	seq.Close()
+	<-seq.Done()
+	require.Equal(t, []string{
+		"start db", "start cache", "start api",
+		"closed api", "closed cache", "closed db",
+	}, events)
+
+	events = nil
+	errBind := errors.New("bind failed")
+	_, err = process.Sequence(p, "service", service("db"), &process.Task{
+		Label:   "api",
+		OnStart: func(ctx process.Context) error { return errBind },
+	})
+	require.ErrorIs(t, err, errBind)
+	require.Equal(t, []string{"start db", "closed db"}, events)
+	require.Equal(t, 0, len(p.GetChildren(nil)))
+}
+
+func TestClosePriority(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+
+	var mu sync.Mutex
+	var closed []string
+	start := func(label string, priority int) {
+		p.StartChild(&process.Task{
+			Label:         label,
+			ClosePriority: priority,
+			OnClosed: func() {
+				time.Sleep(time.Millisecond)
+				mu.Lock()
+				closed = append(closed, label)
+				mu.Unlock()
+			},
+		})
+	}
+	start("flusher", 0)
+	start("pipeline", 1)
+	start("listener", 2)
+	start("flusher", 0)
+	start("listener", 2)
+
+	p.Close()
+	<-p.Done()
+	require.Equal(t, []string{"listener", "listener", "pipeline", "flusher", "flusher"}, closed)
+
+	// A Cleanup child isn't waited on with its priority, so it can outlive lower priority siblings
+	p, _ = process.Start(&process.Task{Label: "root"})
+	closed = nil
+	start("pipeline", 0)
+	pipeline := p.GetChild("pipeline")
+	p.StartChild(&process.Task{
+		Label:         "drain",
+		ClosePriority: 1,
+		Cleanup:       true,
+		IdleClose:     time.Nanosecond,
+		OnRun: func(ctx process.Context) {
+			<-pipeline.Done()
+		},
+		OnClosed: func() {
+			mu.Lock()
+			closed = append(closed, "drain")
+			mu.Unlock()
+		},
+	})
+	p.Close()
+	select {
+	case <-p.Done():
+	case <-time.After(time.Second):
+		t.Fatal("close waited on a Cleanup child")
+	}
+	require.Equal(t, []string{"pipeline", "drain"}, closed)
+}
+
+func TestBroadcast(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	var received []string
+	handler := func(ctx process.Context, msg any) {
+		if msg == "panic" {
+			panic(msg)
+		}
+		received = append(received, ctx.Label()+":"+msg.(string))
+	}
+	pipeline, _ := p.StartChild(&process.Task{Label: "pipeline", OnMessage: handler})
+	pipeline.StartChild(&process.Task{Label: "stage"})
+	sink, _ := pipeline.StartChild(&process.Task{
+		Label:     "sink",
+		OnMessage: handler,
+		OnPanic:   func(ctx process.Context, recovered any, stack []byte) {},
+	})
+
+	p.Broadcast("flush")
+	require.Equal(t, []string{"pipeline:flush", "sink:flush"}, received)
+
+	received = nil
+	pipeline.Broadcast("reload")
+	require.Equal(t, []string{"sink:reload"}, received)
+
+	sink.Broadcast("ignored")
+	pipeline.Broadcast("panic")
+	<-sink.Done()
+	require.Equal(t, process.CloseKind_Failed, sink.CloseKind())
+}
+
+func TestReload(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	reloads := make(chan string, 8)
+	errBadConfig := errors.New("bad config")
+	p.StartChild(&process.Task{
+		Label: "api",
+		OnReload: func(ctx process.Context) error {
+			reloads <- ctx.Label()
+			return nil
+		},
+	})
+	db, _ := p.StartChild(&process.Task{
+		Label: "db",
+		OnReload: func(ctx process.Context) error {
+			return errBadConfig
+		},
+	})
+
+	err := p.Reload()
+	var reloadErr *process.ReloadError
+	require.True(t, errors.As(err, &reloadErr))
+	require.Equal(t, []process.Context{db}, reloadErr.Failed)
+	require.Equal(t, []error{errBadConfig}, reloadErr.Errs)
+	require.Equal(t, "api", <-reloads)
+	requireDone(t, db.Closing(), false)
+
+	stop := process.ReloadOnSignal(p)
+	defer stop()
+	proc, _ := os.FindProcess(os.Getpid())
+	require.NoError(t, proc.Signal(syscall.SIGHUP))
+	select {
+	case <-reloads:
+	case <-time.After(time.Second):
+		t.Fatal("SIGHUP did not reload")
+	}
+}
+
+func TestReportError(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	errTimeout := errors.New("timeout")
+	session, _ := p.StartChild(&process.Task{Label: "session", IdleClose: time.Nanosecond})
+	reader, _ := session.StartChild(&process.Task{Label: "reader"})
+	reader.ReportError(errTimeout)
+	reader.ReportError(nil)
+	group, _ := p.StartChild(&process.Task{Label: "group"})
+	fetch, _ := group.GoErr("fetch", func(ctx process.Context) error { return errTimeout })
+	group.Wait()
+
+	paths := func() []string {
+		var paths []string
+		for _, err := range p.Errors() {
+			require.ErrorIs(t, err, errTimeout)
+			var ctxErr *process.ContextError
+			require.True(t, errors.As(err, &ctxErr))
+			paths = append(paths, ctxErr.Path)
+		}
+		return paths
+	}
+	require.ElementsMatch(t, []string{reader.ContextPath(), fetch.ContextPath()}, paths())
+	require.Equal(t, reader.ContextPath()+": timeout", reader.Errors()[0].Error())
+
+	reader.Close()
+	<-session.Done()
+	<-group.Done()
+	require.ElementsMatch(t, []string{reader.ContextPath(), fetch.ContextPath()}, paths())
+}
+
+func TestLimiter(t *testing.T) {
+	clock := ptest.NewClock(time.Now())
+	p, _ := process.Start(&process.Task{Label: "root", Clock: clock})
+	defer p.Close()
+
+	handler, _ := p.StartChild(&process.Task{Label: "handler"})
+	limiter := p.Limiter("db", 10, 2)
+	require.Equal(t, limiter, handler.Limiter("db", 1, 1))
+	require.True(t, limiter.Allow())
+	require.True(t, limiter.Allow())
+	require.False(t, limiter.Allow())
+
+	chWaited := make(chan error)
+	go func() {
+		chWaited <- limiter.Wait()
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(100 * time.Millisecond)
+	require.NoError(t, <-chWaited)
+
+	go func() {
+		chWaited <- limiter.Wait()
+	}()
+	clock.BlockUntil(1)
+	p.Close()
+	require.ErrorIs(t, <-chWaited, process.ErrClosing)
+}
+
+func TestSemaphore(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+
+	sem := p.Semaphore("uploads", 2)
+	require.NoError(t, sem.Acquire())
+	require.True(t, sem.TryAcquire())
+	require.False(t, sem.TryAcquire())
+	sem.Release()
+	require.NoError(t, sem.Acquire())
+
+	chAcquired := make(chan error)
+	go func() {
+		chAcquired <- sem.Acquire()
+	}()
+	p.Close()
+	require.ErrorIs(t, <-chAcquired, process.ErrClosing)
+}
+
+func TestQueue(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	gate := make(chan struct{})
+	var handled int32
+	handler := func(ctx process.Context, job int) {
+		<-gate
+		if job < 0 {
+			panic("bad job")
+		}
+		atomic.AddInt32(&handled, 1)
+	}
+
+	// Drain: queued jobs are handled before the queue is Done
+	q, err := process.Queue(p, "drain", 4, handler, &process.QueueOpts{Full: process.QueueFull_Reject})
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, q.Enqueue(i)) // the worker holds one
+		for i == 0 && q.Len() > 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	require.ErrorIs(t, q.Enqueue(5), process.ErrQueueFull)
+	q.Close()
+	require.ErrorIs(t, q.Enqueue(6), process.ErrClosed)
+	close(gate)
+	<-q.Done()
+	require.Equal(t, int32(5), atomic.LoadInt32(&handled))
+
+	// Discard: the job in progress completes but queued jobs are dropped
+	gate = make(chan struct{})
+	atomic.StoreInt32(&handled, 0)
+	q, _ = process.Queue(p, "discard", 4, handler, &process.QueueOpts{OnClose: process.QueueClose_Discard})
+	q.Enqueue(-1)
+	q.Enqueue(1)
+	q.Enqueue(2)
+	for q.Len() > 2 {
+		time.Sleep(time.Millisecond)
+	}
+	q.Close()
+	close(gate)
+	<-q.Done()
+	require.Equal(t, int32(0), atomic.LoadInt32(&handled))
+	require.Len(t, p.Errors(), 1)
+}
+
+func TestStats(t *testing.T) {
+	clock := ptest.NewClock(time.Now())
+	p, _ := process.Start(&process.Task{Label: "root", Clock: clock})
+	defer p.Close()
+
+	running := make(chan struct{})
+	worker, _ := p.StartChild(&process.Task{
+		Label: "worker",
+		OnStart: func(ctx process.Context) error {
+			clock.Advance(time.Second)
+			return nil
+		},
+		OnRun: func(ctx process.Context) {
+			close(running)
+			<-ctx.Closing()
+		},
+		OnClosing: func() {
+			clock.Advance(3 * time.Second)
+		},
+	})
+	<-running
+	worker.StartChild(&process.Task{Label: "a"})
+	b, _ := worker.StartChild(&process.Task{Label: "b"})
+	b.Close()
+	<-b.Done()
+	clock.Advance(2 * time.Second)
+
+	stats := worker.Stats()
+	require.Equal(t, 3*time.Second, stats.Uptime)
+	require.Equal(t, time.Second, stats.StartDuration)
+	require.Equal(t, 2*time.Second, stats.RunDuration)
+	require.Equal(t, int64(2), stats.ChildrenStarted)
+	require.Equal(t, 1, stats.ChildCount)
+	require.Zero(t, stats.CloseLatency)
+	require.Equal(t, stats, process.TreeSnapshot(p).Children[0].Stats)
+
+	worker.Close()
+	<-worker.Done()
+	stats = worker.Stats()
+	require.Equal(t, 3*time.Second, stats.CloseLatency)
+	require.Equal(t, 6*time.Second, stats.Uptime)
+	require.Equal(t, 0, stats.ChildCount)
+}
+
+func TestHistory(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root", HistorySize: 4})
+	defer p.Close()
+
+	session, _ := p.StartChild(&process.Task{Label: "session"})
+	session.SetLogLevel(log.Warn)
+	session.Debugf("handshake %d", 1) // retained even though not emitted
+	session.Info(2, "reading")
+	session.Warn("slow peer")
+	reader, _ := session.StartChild(&process.Task{Label: "reader"})
+	reader.Close()
+	<-reader.Done()
+
+	var events []string
+	for _, entry := range session.History() {
+		events = append(events, entry.Event+" "+entry.Msg)
+	}
+	require.Equal(t, []string{
+		"info reading",
+		"warning slow peer",
+		"child started " + reader.ContextPath(),
+		"child closed " + reader.ContextPath(),
+	}, events)
+	require.Equal(t, "closed", reader.History()[len(reader.History())-1].Event)
+
+	text := &strings.Builder{}
+	process.PrintTree(p, text)
+	require.Contains(t, text.String(), "warning: slow peer")
+}
+
+func TestMutex(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	var mu process.Mutex
+	require.NoError(t, mu.Lock(p))
+	require.False(t, mu.TryLock())
+
+	worker, _ := p.StartChild(&process.Task{Label: "worker"})
+	chLocked := make(chan error)
+	go func() {
+		chLocked <- mu.Lock(worker)
+	}()
+	worker.Close()
+	require.ErrorIs(t, <-chLocked, process.ErrClosing)
+
+	mu.Unlock()
+	require.True(t, mu.TryLock())
+	mu.Unlock()
+	require.Panics(t, mu.Unlock)
+}
+
+func TestOnce(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	var once process.Once
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go once.Do(p, func() {
+		atomic.AddInt32(&calls, 1)
+		close(started)
+		<-release
+	})
+	<-started
+
+	waiter, _ := p.StartChild(&process.Task{Label: "waiter"})
+	chDone := make(chan error)
+	go func() {
+		chDone <- once.Do(waiter, func() { atomic.AddInt32(&calls, 1) })
+	}()
+	waiter.Close()
+	require.ErrorIs(t, <-chDone, process.ErrClosing)
+
+	close(release)
+	require.NoError(t, once.Do(p, func() { atomic.AddInt32(&calls, 1) }))
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestStartWhileClosing(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+
+	flushed := make(chan struct{})
+	var lateErr, cleanupErr error
+	var svc process.Context
+	svc, _ = p.StartChild(&process.Task{
+		Label: "svc",
+		OnClosing: func() {
+			_, lateErr = svc.StartChild(&process.Task{Label: "late"})
+			_, cleanupErr = svc.StartChild(&process.Task{
+				Label:     "flush",
+				Cleanup:   true,
+				IdleClose: time.Nanosecond,
+				OnRun: func(ctx process.Context) {
+					time.Sleep(10 * time.Millisecond)
+					close(flushed)
+				},
+			})
+		},
+	})
+	svc.Close()
+	<-svc.Done()
+	require.ErrorIs(t, lateErr, process.ErrClosing)
+	require.NoError(t, cleanupErr)
+	requireDone(t, flushed, true)
+
+	_, err := svc.StartChild(&process.Task{Label: "after", Cleanup: true})
+	require.ErrorIs(t, err, process.ErrClosed)
+	p.Close()
+}
+
+func TestHealthFlapping(t *testing.T) {
+	clock := ptest.NewClock(time.Now())
+	p, _ := process.Start(&process.Task{Label: "root", Clock: clock})
+	defer p.Close()
+
+	// The worker fails each of its first few runs, restarting immediately each time
+	var runs int32
+	p.StartChild(&process.Task{
+		Label:         "worker",
+		RestartPolicy: process.RestartOnFailure,
+		OnRunErr: func(ctx process.Context) error {
+			if atomic.AddInt32(&runs, 1) <= int32(process.FlapThreshold) {
+				return errors.New("crashed")
+			}
+			<-ctx.Closing()
+			return nil
+		},
+	})
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&runs) > int32(process.FlapThreshold) && p.GetChild("worker") != nil
+	}, time.Second, time.Millisecond)
+
+	// Running again, but restarted too often to be considered healthy
+	require.Equal(t, process.Degraded, p.Health())
+	require.Equal(t, process.Degraded, p.HealthReport().Status)
+
+	clock.Advance(process.FlapWindow)
+	require.Eventually(t, func() bool { return p.Health() == process.Healthy }, time.Second, time.Millisecond)
+}
+
+func TestHealthReport(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	dbDown := int32(1)
+	db, _ := p.StartChild(&process.Task{
+		Label:    "db",
+		Critical: true,
+		HealthCheck: func(ctx process.Context) error {
+			if atomic.LoadInt32(&dbDown) != 0 {
+				return errors.New("connection refused")
+			}
+			return nil
+		},
+	})
+	p.StartChild(&process.Task{
+		Label:       "cache",
+		HealthCheck: func(ctx process.Context) error { return nil },
+	})
+
+	report := p.HealthReport()
+	require.Equal(t, process.Unhealthy, report.Status)
+	require.Len(t, report.Children, 2)
+	require.Equal(t, "db", report.Children[0].Label)
+	require.Equal(t, process.Unhealthy, report.Children[0].Status)
+	require.Equal(t, "connection refused", report.Children[0].Err)
+	require.Equal(t, process.Healthy, report.Children[1].Status)
+
+	srv := httptest.NewServer(process.HealthHandler(p))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	var got process.HealthReport
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	resp.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	require.Equal(t, db.ContextID(), got.Children[0].ID)
+
+	atomic.StoreInt32(&dbDown, 0)
+	resp, err = http.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	ready := httptest.NewServer(process.ReadyHandler(p))
+	defer ready.Close()
+	resp, err = http.Get(ready.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	p.Drain()
+	resp, err = http.Get(ready.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestCloseAndWait(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	p.StartChild(&process.Task{Label: "quick"})
+	require.NoError(t, p.CloseAndWait(time.Second))
+
+	release := make(chan struct{})
+	p, _ = process.Start(&process.Task{Label: "root"})
+	p.StartChild(&process.Task{
+		Label:     "stuck",
+		OnClosing: func() { <-release },
+	})
+	err := p.CloseAndWait(20 * time.Millisecond)
+	var timeoutErr *process.CloseTimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	require.Len(t, timeoutErr.Stuck, 2)
+	require.Contains(t, timeoutErr.Stuck[1], "/stuck#")
+	require.Contains(t, err.Error(), "2 context(s) still closing")
+
+	close(release)
+	require.NoError(t, p.CloseAndWait(0))
+}
+
+func TestPause(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	var units int32
+	worker, _ := p.StartChild(&process.Task{
+		Label: "worker",
+		OnRun: func(ctx process.Context) {
+			for ctx.Checkpoint() == nil {
+				atomic.AddInt32(&units, 1)
+				time.Sleep(time.Millisecond)
+			}
+		},
+	})
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&units) > 0 }, time.Second, time.Millisecond)
+
+	p.Pause()
+	require.True(t, worker.IsPaused())
+	require.Equal(t, "paused", process.TreeSnapshot(worker).State)
+	time.Sleep(5 * time.Millisecond)
+	paused := atomic.LoadInt32(&units)
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(t, paused, atomic.LoadInt32(&units))
+
+	// Resuming a descendant doesn't override a paused ancestor
+	worker.Resume()
+	require.True(t, worker.IsPaused())
+
+	p.Resume()
+	require.False(t, worker.IsPaused())
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&units) > paused }, time.Second, time.Millisecond)
+
+	// Closing releases a paused Checkpoint()
+	worker.Pause()
+	worker.Close()
+	<-worker.Done()
+	require.ErrorIs(t, worker.Checkpoint(), process.ErrClosing)
+}
+
+func TestGetChildrenOptions(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	server, _ := p.StartChild(&process.Task{Label: "server"})
+	for i := 0; i < 3; i++ {
+		session, _ := server.StartChild(&process.Task{Label: fmt.Sprintf("session/%d", i)})
+		session.StartChild(&process.Task{Label: "reader"})
+	}
+	stuck := make(chan struct{})
+	defer close(stuck)
+	closing, _ := server.StartChild(&process.Task{
+		Label:     "session/closing",
+		OnClosing: func() { <-stuck },
+	})
+	go closing.Close()
+	require.Eventually(t, func() bool { return closing.State() == process.Closing }, time.Second, time.Millisecond)
+
+	require.Len(t, p.GetChildren(nil), 1)
+	require.Len(t, p.GetChildren(nil, process.WithRecursive()), 8)
+	require.Len(t, p.GetChildren(nil, process.WithLabelPrefix("session/")), 0)
+	require.Len(t, p.GetChildren(nil, process.WithRecursive(), process.WithLabelPrefix("session/")), 4)
+	require.Len(t, p.GetChildren(nil, process.WithRecursive(), process.WithLabelPrefix("session/"), process.WithState(process.Running)), 3)
+	require.Equal(t, []process.Context{closing}, server.GetChildren(nil, process.WithState(process.Closing)))
+
+	// A child whose OnStart is still running is Starting, not Running
+	release := make(chan struct{})
+	go server.StartChild(&process.Task{
+		Label: "session/starting",
+		OnStart: func(ctx process.Context) error {
+			<-release
+			return nil
+		},
+		OnMessage: func(ctx process.Context, msg any) {
+			t.Errorf("%s received %v while starting", ctx.Label(), msg)
+		},
+		OnReload: func(ctx process.Context) error {
+			t.Errorf("%s reloaded while starting", ctx.Label())
+			return nil
+		},
+	})
+	require.Eventually(t, func() bool {
+		return len(server.GetChildren(nil, process.WithState(process.Starting))) == 1
+	}, time.Second, time.Millisecond)
+	require.Len(t, p.GetChildren(nil, process.WithRecursive(), process.WithLabelPrefix("session/"), process.WithState(process.Running)), 3)
+	server.Broadcast("flush")
+	require.NoError(t, server.Reload())
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return len(server.GetChildren(nil, process.WithState(process.Running))) == 4
+	}, time.Second, time.Millisecond)
+}
+
+func TestTags(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	ingest, _ := p.StartChild(&process.Task{Label: "ingest", Tags: map[string]string{"component": "ingest", "tier": "1"}})
+	ingest.StartChild(&process.Task{Label: "reader", Tags: map[string]string{"component": "ingest"}})
+	p.StartChild(&process.Task{Label: "api", Tags: map[string]string{"component": "api"}})
+
+	require.Equal(t, "1", ingest.Tags()["tier"])
+	require.Nil(t, p.Tags())
+	require.Len(t, p.GetChildren(nil, process.WithRecursive(), process.WithTag("component", "ingest")), 2)
+	require.Equal(t, []process.Context{ingest}, p.GetChildren(nil, process.WithRecursive(), process.WithTag("component", "ingest"), process.WithTag("tier", "1")))
+
+	require.Equal(t, "ingest", process.TreeSnapshot(p).Children[0].Tags["component"])
+	out := &strings.Builder{}
+	process.PrintTree(ingest, out)
+	require.Contains(t, out.String(), "# component=ingest tier=1\n")
+}
+
+func TestTaskRefAs(t *testing.T) {
+	type session struct{ user string }
+
+	p, _ := process.Start(&process.Task{Label: "root", TaskRef: &session{user: "alice"}})
+	defer p.Close()
+
+	sess, ok := process.TaskRefAs[*session](p)
+	require.True(t, ok)
+	require.Equal(t, "alice", sess.user)
+	_, ok = process.TaskRefAs[string](p)
+	require.False(t, ok)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p.SetTaskRef(&session{user: fmt.Sprint(i)})
+			_, ok := process.TaskRefAs[*session](p)
+			require.True(t, ok)
+		}(i)
+	}
+	wg.Wait()
+
+	p.SetTaskRef(nil)
+	_, ok = process.TaskRefAs[*session](p)
+	require.False(t, ok)
+	require.Nil(t, p.TaskRef())
+}
+
+func TestLookupID(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root", RegisterIDs: true})
+	child, _ := p.StartChild(&process.Task{Label: "child"})
+	grandchild, _ := child.StartChild(&process.Task{Label: "grandchild"})
+
+	other, _ := process.Start(&process.Task{Label: "other"})
+	defer other.Close()
+
+	require.Equal(t, p, process.LookupID(p.ContextID()))
+	require.Equal(t, grandchild, process.LookupID(grandchild.ContextID()))
+	require.Nil(t, process.LookupID(other.ContextID()))
+
+	process.LookupID(child.ContextID()).Close()
+	<-child.Done()
+	require.Nil(t, process.LookupID(child.ContextID()))
+	require.Nil(t, process.LookupID(grandchild.ContextID()))
+
+	p.Close()
+	<-p.Done()
+	require.Nil(t, process.LookupID(p.ContextID()))
+}
+
+func TestCloseOrdering(t *testing.T) {
+	var mu sync.Mutex
+	var violations []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			mu.Lock()
+			violations = append(violations, fmt.Sprintf(format, args...))
+			mu.Unlock()
+		}
+	}
+
+	// startNode starts a Context with the given number of descendants per level, checking ordering at each callback
+	var startNode func(parent process.Context, label string, fanout []int) process.Context
+	startNode = func(parent process.Context, label string, fanout []int) process.Context {
+		var self process.Context
+		var children []process.Context
+		task := &process.Task{
+			Label: label,
+			OnStart: func(ctx process.Context) error {
+				check(ctx.State() == process.Starting, "%s: OnStart saw state %v", label, process.StateName(ctx.State()))
+				return nil
+			},
+			OnRun: func(ctx process.Context) {
+				<-ctx.Closing()
+				time.Sleep(time.Millisecond)
+			},
+			OnClosing: func() {
+				check(isDone(t, self.Closing()), "%s: OnClosing before Closing()", label)
+				check(self.State() == process.Closing, "%s: OnClosing saw state %v", label, process.StateName(self.State()))
+				for _, child := range children {
+					check(!isDone(t, child.Closing()), "%s: child closing before OnClosing", label)
+				}
+			},
+			OnClosed: func() {
+				check(self.State() == process.Closed, "%s: OnClosed saw state %v", label, process.StateName(self.State()))
+				check(!isDone(t, self.Done()), "%s: Done() before OnClosed returned", label)
+				process.Walk(self, func(ci process.Context, depth int) bool {
+					check(depth == 0 || isDone(t, ci.Done()), "%s: OnClosed before %s was Done()", label, ci.Label())
+					return true
+				})
+			},
+		}
+		if parent == nil {
+			self, _ = process.Start(task)
+		} else {
+			self, _ = parent.StartChild(task)
+		}
+		check(self.State() == process.Running, "%s: started in state %v", label, process.StateName(self.State()))
+		if len(fanout) > 0 {
+			for i := 0; i < fanout[0]; i++ {
+				children = append(children, startNode(self, fmt.Sprintf("%s/%d", label, i), fanout[1:]))
+			}
+		}
+		return self
+	}
+
+	for i := 0; i < 20; i++ {
+		root := startNode(nil, "root", []int{3, 2})
+		root.Close()
+		<-root.Done()
+		require.Equal(t, process.Closed, root.State())
+	}
+	require.Empty(t, violations)
+}
+
+func TestOnClosingOnDone(t *testing.T) {
+	p, _ := process.Start(&process.Task{Label: "root"})
+
+	var order []string
+	var mu sync.Mutex
+	note := func(event string) func() {
+		return func() {
+			mu.Lock()
+			order = append(order, event)
+			mu.Unlock()
+		}
+	}
+
+	child, _ := p.StartChild(&process.Task{
+		Label:     "child",
+		OnClosing: note("task closing"),
+		OnClosed:  note("task closed"),
+	})
+	child.OnClosing(note("closing"))
+	child.OnDone(note("done"))
+	cancel := child.OnDone(note("canceled"))
+	cancel()
+
+	var fired int32
+	for i := 0; i < 1000; i++ {
+		child.OnDone(func() { atomic.AddInt32(&fired, 1) })
+	}
+
+	child.Close()
+	<-child.Done()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&fired) == 1000 }, time.Second, time.Millisecond)
+
+	mu.Lock()
+	require.Equal(t, []string{"closing", "task closing", "task closed", "done"}, order)
+	mu.Unlock()
+
+	// Registering once past a state calls fn immediately
+	called := false
+	child.OnClosing(func() { called = true })
+	require.True(t, called)
+
+	p.Close()
+	<-p.Done()
+}
+
+func TestBlueprint(t *testing.T) {
+	var mu sync.Mutex
+	var started []string
+	process.RegisterKind("test-service", func(bp *process.Blueprint) (*process.Task, error) {
+		if bp.Config["fail"] != "" {
+			return nil, errors.New("misconfigured")
+		}
+		return &process.Task{
+			OnStart: func(ctx process.Context) error {
+				mu.Lock()
+				started = append(started, ctx.Label())
+				mu.Unlock()
+				return nil
+			},
+		}, nil
+	})
+
+	p, _ := process.Start(&process.Task{Label: "root"})
+	defer p.Close()
+
+	bp := process.Blueprint{
+		Label:      "app",
+		CloseOrder: process.CloseOrder_LIFO,
+		Children: []process.Blueprint{
+			{Kind: "test-service", Label: "api", DependsOn: []string{"db", "cache"}, Tags: map[string]string{"tier": "front"}},
+			{Kind: "test-service", Label: "cache", DependsOn: []string{"db"}},
+			{Kind: "test-service", Label: "db", RestartPolicy: process.RestartOnFailure, Config: map[string]string{"dsn": "x"}},
+		},
+	}
+	app, err := process.StartBlueprint(p, &bp)
+	require.NoError(t, err)
+	require.Equal(t, []string{"db", "cache", "api"}, started)
+	require.Equal(t, "front", process.FindByPath(app, "api").Tags()["tier"])
+
+	// Exporting and restarting the blueprint reproduces the topology
+	exported := process.ExportBlueprint(app)
+	buf, err := json.Marshal(exported)
+	require.NoError(t, err)
+	var decoded process.Blueprint
+	require.NoError(t, json.Unmarshal(buf, &decoded))
+	require.Equal(t, exported, decoded)
+	require.Equal(t, "db", decoded.Children[0].Label)
+	require.Equal(t, "x", decoded.Children[0].Config["dsn"])
+	require.Equal(t, process.RestartOnFailure, decoded.Children[0].RestartPolicy)
+
+	app.Close()
+	<-app.Done()
+	started = nil
+	rebuilt, err := process.StartBlueprint(p, &decoded)
+	require.NoError(t, err)
+	require.Equal(t, []string{"db", "cache", "api"}, started)
+	require.Len(t, rebuilt.GetChildren(nil), 3)
+
+	// Bad blueprints
+	_, err = process.StartBlueprint(p, &process.Blueprint{Label: "x", Kind: "nope"})
+	require.ErrorIs(t, err, process.ErrBadBlueprint)
+	_, err = process.StartBlueprint(p, &process.Blueprint{
+		Label:    "cyclic",
+		Children: []process.Blueprint{{Label: "a", DependsOn: []string{"b"}}, {Label: "b", DependsOn: []string{"a"}}},
+	})
+	require.ErrorIs(t, err, process.ErrBadBlueprint)
+	_, err = process.StartBlueprint(p, &process.Blueprint{
+		Label:    "group",
+		Children: []process.Blueprint{{Label: "ok", Kind: "test-service"}, {Label: "bad", Kind: "test-service", Config: map[string]string{"fail": "1"}}},
+	})
+	require.EqualError(t, err, "group/bad: misconfigured")
+	require.Nil(t, p.GetChild("group"))
+}
+
 func requireDone(t *testing.T, chDone <-chan struct{}, done bool) {
 	t.Helper()
 	require.Equal(t, done, isDone(t, chDone))
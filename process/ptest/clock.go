@@ -0,0 +1,188 @@
+// Package ptest provides helpers for testing code built on package process, such as a synthetic Clock.
+package ptest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/arcspace/go-cedar/process"
+)
+
+// Clock is a process.Clock whose time only moves forward when Advance() is called, allowing tests of IdleClose,
+// timeouts, and schedules to run instantly and deterministically.  Use via process.Task.Clock or process.SetClock().
+//
+// Timers created via AfterFunc() call their fn synchronously from within Advance().
+// As with package time, a timer set for d <= 0 fires immediately (and an AfterFunc fn is then called in its own goroutine).
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	pending []*timer      // sorted by when
+	changed chan struct{} // closed (and replaced) whenever a timer is added
+}
+
+// NewClock returns a Clock whose time starts at the given time (or if zero, 2000-01-01 UTC).
+func NewClock(start time.Time) *Clock {
+	if start.IsZero() {
+		start = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return &Clock{
+		now:     start,
+		changed: make(chan struct{}),
+	}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) NewTimer(d time.Duration) process.Timer {
+	t := &timer{
+		clock: c,
+		ch:    make(chan time.Time, 1),
+	}
+	t.Reset(d)
+	return t
+}
+
+func (c *Clock) AfterFunc(d time.Duration, fn func()) process.Timer {
+	t := &timer{
+		clock: c,
+		fn:    fn,
+	}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves this Clock forward by d, firing timers as their times are reached (in order).
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	for len(c.pending) > 0 && !c.pending[0].when.After(target) {
+		t := c.pending[0]
+		c.pending = c.pending[1:]
+		t.active = false
+		if t.when.After(c.now) {
+			c.now = t.when
+		}
+		now := c.now
+
+		c.mu.Unlock()
+		if t.fn != nil {
+			t.fn()
+		} else {
+			select {
+			case t.ch <- now:
+			default:
+			}
+		}
+		c.mu.Lock()
+	}
+	c.now = target
+	c.mu.Unlock()
+}
+
+// Pending returns the number of timers waiting to fire.
+func (c *Clock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+// BlockUntil blocks until at least n timers are waiting to fire.
+// This allows a test to wait for a goroutine to reach its timer before calling Advance().
+func (c *Clock) BlockUntil(n int) {
+	for {
+		c.mu.Lock()
+		pending, changed := len(c.pending), c.changed
+		c.mu.Unlock()
+
+		if pending >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+// add inserts t into the pending list; c.mu must be held.
+func (c *Clock) add(t *timer) {
+	i := sort.Search(len(c.pending), func(i int) bool {
+		return c.pending[i].when.After(t.when)
+	})
+	c.pending = append(c.pending, nil)
+	copy(c.pending[i+1:], c.pending[i:])
+	c.pending[i] = t
+	t.active = true
+
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// remove removes t from the pending list; c.mu must be held.
+func (c *Clock) remove(t *timer) {
+	for i, ti := range c.pending {
+		if ti == t {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			break
+		}
+	}
+	t.active = false
+}
+
+type timer struct {
+	clock  *Clock
+	when   time.Time
+	ch     chan time.Time
+	fn     func()
+	active bool
+}
+
+func (t *timer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *timer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	wasActive := t.active
+	if wasActive {
+		t.clock.remove(t)
+	}
+	return wasActive
+}
+
+func (t *timer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	wasActive := t.active
+	if wasActive {
+		t.clock.remove(t)
+	}
+	t.when = t.clock.now.Add(d)
+
+	// Like a time.Timer, a timer that is already due fires now rather than on the next Advance()
+	if d <= 0 {
+		now := t.clock.now
+		t.clock.mu.Unlock()
+		t.fire(now)
+		return wasActive
+	}
+	t.clock.add(t)
+	t.clock.mu.Unlock()
+	return wasActive
+}
+
+// fire sends now on t's channel (if not already full) or for AfterFunc() timers, calls fn in its own goroutine
+// (as time.AfterFunc does) since the caller may hold locks that fn needs.
+func (t *timer) fire(now time.Time) {
+	if t.fn != nil {
+		go t.fn()
+		return
+	}
+	select {
+	case t.ch <- now:
+	default:
+	}
+}
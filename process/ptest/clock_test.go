@@ -0,0 +1,152 @@
+package ptest_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arcspace/go-cedar/process"
+	"github.com/arcspace/go-cedar/process/ptest"
+)
+
+func TestClockIdleClose(t *testing.T) {
+	clock := ptest.NewClock(time.Time{})
+	p, _ := process.Start(&process.Task{
+		Label:     "root",
+		Clock:     clock,
+		IdleClose: time.Hour,
+	})
+	defer p.Close()
+
+	p.CloseWhenIdle(time.Hour)
+	clock.BlockUntil(1)
+	clock.Advance(59 * time.Minute)
+	require.Equal(t, process.Running, p.State())
+
+	clock.Advance(time.Minute)
+	<-p.Done()
+	require.Equal(t, process.CloseKind_Idle, p.CloseKind())
+}
+
+func TestClockTimeout(t *testing.T) {
+	clock := ptest.NewClock(time.Time{})
+	p, _ := process.Start(&process.Task{
+		Label: "root",
+		Clock: clock,
+	})
+	defer p.Close()
+
+	child, _ := p.StartChild(&process.Task{
+		Label:   "child",
+		Timeout: time.Minute,
+	})
+	deadline, ok := child.Deadline()
+	require.True(t, ok)
+	require.Equal(t, clock.Now().Add(time.Minute), deadline)
+
+	clock.Advance(time.Minute)
+	<-child.Done()
+	require.ErrorIs(t, child.Err(), context.DeadlineExceeded)
+}
+
+func TestClockZeroTimer(t *testing.T) {
+	clock := ptest.NewClock(time.Time{})
+
+	timer := clock.NewTimer(0)
+	select {
+	case at := <-timer.C():
+		require.Equal(t, clock.Now(), at)
+	case <-time.After(5 * time.Second):
+		t.Fatal("zero-duration timer did not fire")
+	}
+
+	fired := make(chan struct{})
+	clock.AfterFunc(-time.Second, func() { close(fired) })
+	select {
+	case <-fired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("already-due AfterFunc did not fire")
+	}
+	require.Equal(t, 0, clock.Pending())
+}
+
+func TestClockWatchdogAndTree(t *testing.T) {
+	clock := ptest.NewClock(time.Time{})
+	p, _ := process.Start(&process.Task{Label: "root", Clock: clock})
+	defer p.Close()
+
+	chLeaks := make(chan process.Leak, 10)
+	stop := process.StartWatchdog(process.WatchdogOpts{
+		Interval:       time.Millisecond,
+		ClosingTimeout: time.Minute,
+		OnLeaks: func(leaks []process.Leak) {
+			for _, leak := range leaks {
+				chLeaks <- leak
+			}
+		},
+	})
+	defer stop()
+
+	release := make(chan struct{})
+	stuck, _ := p.StartChild(&process.Task{
+		Label:     "stuck",
+		OnClosing: func() { <-release },
+	})
+	stuck.Close()
+	defer close(release)
+
+	// Closing ages are measured by the Context's Clock, so no leak is reported until it advances
+	nextLeak := func(wait time.Duration) *process.Leak {
+		timeout := time.After(wait)
+		for {
+			select {
+			case leak := <-chLeaks:
+				if leak.Context == stuck {
+					return &leak
+				}
+			case <-timeout:
+				return nil
+			}
+		}
+	}
+	require.Nil(t, nextLeak(50*time.Millisecond))
+	clock.Advance(time.Minute)
+	leak := nextLeak(5 * time.Second)
+	require.NotNil(t, leak)
+	require.Equal(t, time.Minute, leak.Age)
+
+	_, err := p.GoAfter(time.Hour, "later", func(ctx process.Context) {})
+	require.NoError(t, err)
+	node := process.TreeSnapshot(p)
+	require.Equal(t, time.Hour, node.Children[1].RunsIn)
+	var buf bytes.Buffer
+	node.WriteText(&buf)
+	require.Contains(t, buf.String(), "runs in 1h0m0s")
+}
+
+func TestClockEvents(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := ptest.NewClock(start)
+	p, _ := process.Start(&process.Task{Label: "root", Clock: clock})
+	defer p.Close()
+
+	events := process.Subscribe(process.EventFilter{
+		Root:  p,
+		Kinds: process.Event_Started | process.Event_Closed,
+	})
+	defer process.Unsubscribe(events)
+
+	// Event times agree with the Context's Clock (and so with its Stats and History)
+	child, _ := p.StartChild(&process.Task{Label: "child"})
+	require.Equal(t, start, (<-events).Time)
+
+	clock.Advance(time.Minute)
+	child.Close()
+	<-child.Done()
+	ev := <-events
+	require.Equal(t, process.Event_Closed, ev.Kind)
+	require.Equal(t, start.Add(time.Minute), ev.Time)
+}
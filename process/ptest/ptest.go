@@ -0,0 +1,135 @@
+package ptest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arcspace/go-cedar/process"
+)
+
+// RequireDoneWithin fails the test immediately if ctx is not Done() within the given timeout.
+func RequireDoneWithin(t testing.TB, ctx process.Context, timeout time.Duration) {
+	t.Helper()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+		t.Fatalf("context %03d %q not done within %v:\n%s", ctx.ContextID(), ctx.Label(), timeout, treeText(ctx))
+	}
+}
+
+// RequireNoLiveChildren fails the test immediately if ctx has any open children.
+func RequireNoLiveChildren(t testing.TB, ctx process.Context) {
+	t.Helper()
+
+	if children := ctx.GetChildren(nil); len(children) > 0 {
+		t.Fatalf("context %03d %q has %d live children:\n%s", ctx.ContextID(), ctx.Label(), len(children), treeText(ctx))
+	}
+}
+
+// CheckLeaks fails the test if any root Context started during the test is not Done() within the given grace period
+// after the test (and its other cleanups) finish.  If grace is 0, one second is used.
+// Tests using CheckLeaks should not run in parallel with other tests that start roots.
+func CheckLeaks(t testing.TB, grace time.Duration) {
+	t.Helper()
+	if grace <= 0 {
+		grace = time.Second
+	}
+
+	existing := make(map[process.Context]struct{})
+	for _, root := range process.Roots() {
+		existing[root] = struct{}{}
+	}
+
+	t.Cleanup(func() {
+		deadline := time.Now().Add(grace)
+		for {
+			var leaked []process.Context
+			for _, root := range process.Roots() {
+				if _, ok := existing[root]; !ok {
+					leaked = append(leaked, root)
+				}
+			}
+			if len(leaked) == 0 {
+				return
+			}
+			if time.Now().After(deadline) {
+				b := &strings.Builder{}
+				for _, root := range leaked {
+					process.PrintTree(root, b)
+				}
+				t.Errorf("%d root context(s) outlived the test:\n%s", len(leaked), b.String())
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+}
+
+// Tree is a throwaway Context tree built by StartTree().
+type Tree struct {
+	Root  process.Context
+	Nodes map[string]process.Context // by path, where the root is ""
+}
+
+// StartTree starts a root labeled "root" and a child for each of the given '/' separated label paths (see FindByPath),
+// where missing intermediate Contexts are also started.  The tree is closed (and required to be done) when the test finishes.
+func StartTree(t testing.TB, paths ...string) *Tree {
+	t.Helper()
+
+	root, err := process.Start(&process.Task{Label: "root"})
+	if err != nil {
+		t.Fatalf("starting root: %v", err)
+	}
+	tree := &Tree{
+		Root:  root,
+		Nodes: map[string]process.Context{"": root},
+	}
+	t.Cleanup(func() {
+		root.Close()
+		RequireDoneWithin(t, root, 5*time.Second)
+	})
+
+	for _, path := range paths {
+		parent, prefix := root, ""
+		for _, label := range strings.Split(strings.Trim(path, "/"), "/") {
+			if label == "" {
+				continue
+			}
+			if prefix != "" {
+				prefix += "/"
+			}
+			prefix += label
+			node, ok := tree.Nodes[prefix]
+			if !ok {
+				if node, err = parent.StartChild(&process.Task{Label: label}); err != nil {
+					t.Fatalf("starting %q: %v", prefix, err)
+				}
+				tree.Nodes[prefix] = node
+			}
+			parent = node
+		}
+	}
+	return tree
+}
+
+// Get returns the Context started for the given path, failing the test if there is none.
+func (tree *Tree) Get(t testing.TB, path string) process.Context {
+	t.Helper()
+
+	node, ok := tree.Nodes[strings.Trim(path, "/")]
+	if !ok {
+		t.Fatalf("no context at %q", path)
+	}
+	return node
+}
+
+func treeText(ctx process.Context) string {
+	b := &strings.Builder{}
+	process.PrintTree(ctx, b)
+	return b.String()
+}
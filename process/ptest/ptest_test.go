@@ -0,0 +1,27 @@
+package ptest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arcspace/go-cedar/process"
+	"github.com/arcspace/go-cedar/process/ptest"
+)
+
+func TestStartTree(t *testing.T) {
+	ptest.CheckLeaks(t, 0)
+
+	tree := ptest.StartTree(t, "grpc/server/session-1", "grpc/server/session-2", "db")
+	require.Len(t, tree.Nodes, 6)
+
+	server := tree.Get(t, "grpc/server")
+	require.Equal(t, server, process.FindByPath(tree.Root, "grpc/server"))
+	require.Equal(t, 2, len(server.GetChildren(nil)))
+
+	db := tree.Get(t, "db")
+	ptest.RequireNoLiveChildren(t, db)
+	db.Close()
+	ptest.RequireDoneWithin(t, db, time.Second)
+}
@@ -0,0 +1,137 @@
+package process
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrQueueFull is returned by JobQueue.Enqueue() when the queue is full and its QueueOpts.Full policy is QueueFull_Reject.
+var ErrQueueFull = errors.New("queue full")
+
+// QueueFullPolicy specifies what JobQueue.Enqueue() does when the queue is at capacity.
+type QueueFullPolicy int
+
+const (
+	QueueFull_Block  QueueFullPolicy = iota // Enqueue blocks until there is room (or the queue closes)
+	QueueFull_Reject                        // Enqueue returns ErrQueueFull
+)
+
+// QueueClosePolicy specifies what happens to queued jobs when a JobQueue closes.
+type QueueClosePolicy int
+
+const (
+	QueueClose_Drain   QueueClosePolicy = iota // Workers handle all queued jobs before the queue is Done
+	QueueClose_Discard                         // Queued jobs that no worker has started are dropped
+)
+
+// QueueOpts specifies how a JobQueue behaves.
+type QueueOpts struct {
+	Workers int              // Number of worker Contexts handling jobs (1 if 0)
+	Full    QueueFullPolicy  // What Enqueue() does when the queue is at capacity
+	OnClose QueueClosePolicy // What happens to queued jobs when the queue closes
+}
+
+// JobQueue is a bounded queue of jobs handled by worker Contexts (see Queue).
+type JobQueue[T any] struct {
+	Context
+	opts    QueueOpts
+	handler func(ctx Context, job T)
+	jobs    chan T
+	mu      sync.RWMutex // held for writing once jobs is closed
+	closed  bool
+}
+
+// Queue starts a JobQueue as a child of parent that holds up to capacity jobs, where opts may be nil.
+// Each job is passed to handler on one of the queue's worker Contexts (children of the queue), so handler
+// should not retain ctx and should cope with ctx.Closing() having fired while a closing queue drains.
+// If handler panics, the panic is reported via the worker's ReportError() and the worker continues with the next job.
+func Queue[T any](parent Context, label string, capacity int, handler func(ctx Context, job T), opts *QueueOpts) (*JobQueue[T], error) {
+	q := &JobQueue[T]{
+		handler: handler,
+		jobs:    make(chan T, capacity),
+	}
+	if opts != nil {
+		q.opts = *opts
+	}
+	if q.opts.Workers < 1 {
+		q.opts.Workers = 1
+	}
+
+	var err error
+	q.Context, err = parent.StartChild(&Task{
+		Label: label,
+		OnClosing: func() {
+			q.mu.Lock()
+			q.closed = true
+			close(q.jobs)
+			q.mu.Unlock()
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < q.opts.Workers; i++ {
+		if _, err := q.Go("worker", q.work); err != nil {
+			q.Close()
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+// Enqueue adds job to the queue, returning ErrClosed if the queue is closing (or closes while Enqueue is blocked).
+// If the queue is full, Enqueue blocks or returns ErrQueueFull as specified by QueueOpts.Full.
+func (q *JobQueue[T]) Enqueue(job T) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+	select {
+	case <-q.Closing():
+		return ErrClosed
+	case q.jobs <- job:
+		return nil
+	default:
+	}
+
+	if q.opts.Full == QueueFull_Reject {
+		return ErrQueueFull
+	}
+	select {
+	case q.jobs <- job:
+		return nil
+	case <-q.Closing():
+		return ErrClosed
+	}
+}
+
+// Len returns the number of jobs waiting to be handled.
+func (q *JobQueue[T]) Len() int {
+	return len(q.jobs)
+}
+
+func (q *JobQueue[T]) work(worker Context) {
+	for {
+		job, ok := <-q.jobs
+		if !ok {
+			return
+		}
+		if q.opts.OnClose == QueueClose_Discard {
+			select {
+			case <-q.Closing():
+				return
+			default:
+			}
+		}
+		err := worker.(*ctx).callRecover(func() error {
+			q.handler(worker, job)
+			return nil
+		})
+		if err != nil {
+			worker.ReportError(err)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package process
+
+import "sync"
+
+// gRegistry indexes all open Contexts started with Task.RegisterIDs (or under a Context that was) by ContextID().
+var gRegistry = struct {
+	sync.Mutex
+	byID map[int64]*ctx
+}{
+	byID: make(map[int64]*ctx),
+}
+
+// LookupID returns the open Context having the given ContextID(), or nil if there is none.
+// Only Contexts started with Task.RegisterIDs set (or descending from one) can be found,
+// allowing admin tooling to act on a specific Context (e.g. "close 48231") without walking the tree by label.
+func LookupID(id int64) Context {
+	gRegistry.Lock()
+	defer gRegistry.Unlock()
+
+	if p, found := gRegistry.byID[id]; found {
+		return p
+	}
+	return nil
+}
+
+func (p *ctx) addToRegistry() {
+	gRegistry.Lock()
+	gRegistry.byID[p.id] = p
+	gRegistry.Unlock()
+}
+
+func (p *ctx) removeFromRegistry() {
+	gRegistry.Lock()
+	delete(gRegistry.byID, p.id)
+	gRegistry.Unlock()
+}
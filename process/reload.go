@@ -0,0 +1,87 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// ReloadError is returned by Reload() if any OnReload hooks fail.
+type ReloadError struct {
+	Failed []Context // Contexts whose OnReload failed, in the order they were reloaded
+	Errs   []error   // Errs[i] is the error returned by Failed[i]
+}
+
+func (err *ReloadError) Error() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "reload failed for %d context(s)", len(err.Failed))
+	for i, ci := range err.Failed {
+		fmt.Fprintf(b, "; %s: %v", ci.Label(), err.Errs[i])
+	}
+	return b.String()
+}
+
+func (p *ctx) Reload() error {
+	var failed *ReloadError
+	Walk(p, func(ci Context, depth int) bool {
+		child := ci.(*ctx)
+		if child.task.OnReload == nil || child.State() != Running {
+			return true
+		}
+		err := child.callRecover(func() error {
+			return child.task.OnReload(child)
+		})
+		if err != nil {
+			if failed == nil {
+				failed = &ReloadError{}
+			}
+			failed.Failed = append(failed.Failed, child)
+			failed.Errs = append(failed.Errs, err)
+		}
+		return true
+	})
+
+	if failed != nil {
+		return failed
+	}
+	return nil
+}
+
+// ReloadOnSignal calls root.Reload() upon receiving any of the given signals (or SIGHUP if none are given), logging any failures.
+// Signal handling stops once root is Closing() or the returned func is called.
+func ReloadOnSignal(root Context, sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+
+	chSig := make(chan os.Signal, 1)
+	chStop := make(chan struct{})
+	signal.Notify(chSig, sigs...)
+
+	go func() {
+		defer signal.Stop(chSig)
+		for {
+			select {
+			case sig := <-chSig:
+				root.Infof(0, "received %v, reloading", sig)
+				if err := root.Reload(); err != nil {
+					root.Warnf("%v", err)
+				}
+			case <-root.Closing():
+				return
+			case <-chStop:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(chStop)
+		})
+	}
+}
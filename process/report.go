@@ -0,0 +1,65 @@
+package process
+
+// MaxReportedErrors caps how many reported errors each Context retains (see Context.Errors).
+var MaxReportedErrors = 100
+
+// ContextError is an error reported via ReportError(), along with the ContextPath() of the Context that reported it.
+type ContextError struct {
+	Path string
+	Err  error
+}
+
+func (err *ContextError) Error() string {
+	return err.Path + ": " + err.Err.Error()
+}
+
+func (err *ContextError) Unwrap() error {
+	return err.Err
+}
+
+func (p *ctx) ReportError(err error) {
+	if err == nil {
+		return
+	}
+	reported := &ContextError{
+		Path: p.ContextPath(),
+		Err:  err,
+	}
+
+	p.subsMu.Lock()
+	p.appendReported(reported)
+	p.subsMu.Unlock()
+}
+
+func (p *ctx) Errors() []error {
+	var errs []error
+	Walk(p, func(ci Context, depth int) bool {
+		child := ci.(*ctx)
+		child.subsMu.Lock()
+		errs = append(errs, child.reported...)
+		child.subsMu.Unlock()
+		return true
+	})
+	return errs
+}
+
+// appendReported adds the given errors to those retained by this Context, dropping the oldest beyond MaxReportedErrors.
+// p.subsMu must be held.
+func (p *ctx) appendReported(errs ...error) {
+	if len(errs) == 0 {
+		return
+	}
+	p.reported = append(p.reported, errs...)
+	if max := MaxReportedErrors; max > 0 && len(p.reported) > max {
+		p.reported = append([]error(nil), p.reported[len(p.reported)-max:]...)
+	}
+}
+
+// takeReported removes and returns the errors retained by this Context so that they can be passed to its parent once it closes.
+func (p *ctx) takeReported() []error {
+	p.subsMu.Lock()
+	defer p.subsMu.Unlock()
+	errs := p.reported
+	p.reported = nil
+	return errs
+}
@@ -0,0 +1,126 @@
+package process
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/arcspace/go-cedar/utils"
+)
+
+// RestartPolicy specifies when a parent automatically restarts a child after it closes.
+type RestartPolicy int32
+
+const (
+	RestartNever     RestartPolicy = iota // The child is never restarted (default)
+	RestartOnFailure                      // The child is restarted if it closes with CloseKind_Failed
+	RestartAlways                         // The child is restarted unless it was explicitly closed (CloseKind_Cancelled)
+)
+
+// A restartable child is flapping once it has been restarted FlapThreshold times within FlapWindow (per its Clock),
+// during which its parent reports Degraded health (see Context.Health).
+var (
+	FlapThreshold = 3
+	FlapWindow    = time.Minute
+)
+
+// restartState is shared across all incarnations of a restartable child.
+type restartState struct {
+	task     Task // pristine copy of the Task originally passed to StartChild()
+	restarts int
+	backoff  utils.ExponentialBackoff
+	disabled bool      // set if the initial start failed, since only restarts are retried
+	startPCs []uintptr // stack that started the first incarnation (see captureStartStack)
+
+	mu          sync.Mutex
+	restartedAt []time.Time // times of the most recent restarts (at most FlapThreshold)
+}
+
+// recordRestart notes that a restart was scheduled at the given time.
+func (rs *restartState) recordRestart(now time.Time) {
+	rs.mu.Lock()
+	rs.restartedAt = append(rs.restartedAt, now)
+	if extra := len(rs.restartedAt) - FlapThreshold; extra > 0 {
+		rs.restartedAt = append(rs.restartedAt[:0], rs.restartedAt[extra:]...)
+	}
+	rs.mu.Unlock()
+}
+
+// flapping returns true if FlapThreshold restarts have been scheduled within FlapWindow of the given time.
+func (rs *restartState) flapping(now time.Time) bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	n := len(rs.restartedAt)
+	return FlapThreshold > 0 && n >= FlapThreshold && now.Sub(rs.restartedAt[n-FlapThreshold]) < FlapWindow
+}
+
+func newRestartState(task *Task) *restartState {
+	rs := &restartState{
+		task:    *task,
+		backoff: newBackoff(task.RestartBackoff, task.RestartMaxBackoff),
+	}
+	return rs
+}
+
+// shouldRestart returns true if the given closed child is eligible to be restarted by its parent.
+func (p *ctx) shouldRestart(child *ctx) bool {
+	rs := child.restart
+	if rs == nil || rs.disabled || atomic.LoadInt32(&p.state) != Running {
+		return false
+	}
+
+	switch kind := CloseKind(atomic.LoadInt32(&child.closeKind)); rs.task.RestartPolicy {
+	case RestartOnFailure:
+		if kind != CloseKind_Failed {
+			return false
+		}
+	case RestartAlways:
+		if kind == CloseKind_Cancelled {
+			return false
+		}
+	default:
+		return false
+	}
+
+	if max := rs.task.MaxRestarts; max > 0 && rs.restarts >= max {
+		child.Warnf("not restarting since MaxRestarts (%d) reached", max)
+		return false
+	}
+	return true
+}
+
+// scheduleRestart restarts the given closed child after its backoff delay elapses.
+// The parent is kept busy (and so won't idle-close) while a restart is pending.
+func (p *ctx) scheduleRestart(child *ctx) {
+	p.subsMu.Lock()
+	if atomic.LoadInt32(&p.state) != Running {
+		p.subsMu.Unlock()
+		return
+	}
+	p.busy.Add(1)
+	p.idle = false
+	p.subsMu.Unlock()
+
+	rs := child.restart
+	rs.restarts++
+	rs.recordRestart(child.clock.Now())
+	delay := rs.backoff.Next()
+	atomic.AddInt32(&p.restarting, 1)
+
+	go func() {
+		defer p.busy.Done()
+		defer atomic.AddInt32(&p.restarting, -1)
+
+		child.Infof(1, "restarting in %v (restart #%d)", delay, rs.restarts)
+		if !p.Sleep(delay) {
+			return
+		}
+
+		task := rs.task
+		if _, err := p.startChild(&task, rs); err != nil {
+			child.Warnf("restart #%d failed: %v", rs.restarts, err)
+		} else if task.Critical {
+			atomic.AddInt32(&p.criticalDown, -1)
+		}
+	}()
+}
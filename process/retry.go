@@ -0,0 +1,109 @@
+package process
+
+import (
+	"errors"
+	"time"
+
+	"github.com/arcspace/go-cedar/utils"
+)
+
+// StartRetryPolicy specifies how StartChild() retries a Task's OnStart (see Task.StartRetry).
+type StartRetryPolicy struct {
+	Retries    int           // Max number of times OnStart is retried after its first attempt fails.
+	Backoff    time.Duration // Delay before the first retry, doubling after each subsequent failed attempt.
+	MaxBackoff time.Duration // Caps the delay between retries; if < Backoff, 64x Backoff is used.
+	Jitter     float64       // Fraction (0..1) that each retry delay is randomly adjusted by.
+
+	// If set, only errors for which this returns true are retried.
+	// Errors marked via Permanent() and panics are never retried.
+	Retryable func(err error) bool
+}
+
+type permanentError struct {
+	err error
+}
+
+func (err *permanentError) Error() string {
+	return err.err.Error()
+}
+
+func (err *permanentError) Unwrap() error {
+	return err.err
+}
+
+// Permanent marks the given error as one that retrying won't resolve, so that returning it from OnStart (or OnRunErr)
+// causes the Context to fail immediately rather than be retried.  The returned error wraps err (see errors.Is).
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// IsPermanent returns true if the given error (or any error it wraps) was marked via Permanent().
+func IsPermanent(err error) bool {
+	var perm *permanentError
+	return errors.As(err, &perm)
+}
+
+// callOnStart calls the Task's OnStart, retrying as specified by Task.StartRetry.
+func (p *ctx) callOnStart() error {
+	policy := retryPolicy{
+		retries:   p.task.StartRetry.Retries,
+		backoff:   newBackoff(p.task.StartRetry.Backoff, p.task.StartRetry.MaxBackoff),
+		jitter:    p.task.StartRetry.Jitter,
+		retryable: p.task.StartRetry.isRetryable,
+	}
+	_, _, err := p.retry("OnStart", &policy, func() error {
+		return p.callRecover(func() error {
+			return p.task.OnStart(p)
+		})
+	})
+	return err
+}
+
+// retryPolicy specifies how retry() retries an operation (see Task.StartRetry and Task.RunRetries).
+type retryPolicy struct {
+	retries   int // max number of retries after the first attempt
+	backoff   utils.ExponentialBackoff
+	jitter    float64
+	retryable func(err error) bool
+}
+
+// newBackoff returns an ExponentialBackoff from min to max, where a max < min means 64x min.
+func newBackoff(min, max time.Duration) utils.ExponentialBackoff {
+	if max < min {
+		max = 64 * min
+	}
+	return utils.ExponentialBackoff{
+		Min: min,
+		Max: max,
+	}
+}
+
+// retry calls attempt until it succeeds, returns an error the given policy doesn't retry, or the policy's retries are used up,
+// sleeping for the policy's backoff between attempts.  If this Context starts closing during a backoff, retrying is abandoned.
+// The number of attempts made and the last error are returned.
+func (p *ctx) retry(what string, policy *retryPolicy, attempt func() error) (attempts int, abandoned bool, err error) {
+	for {
+		err = attempt()
+		attempts++
+		if err == nil || attempts > policy.retries || !policy.retryable(err) {
+			return attempts, false, err
+		}
+
+		delay := utils.Jitter(policy.backoff.Next(), policy.jitter)
+		p.Infof(1, "%s attempt %d failed (retrying in %v): %v", what, attempts, delay, err)
+		if !p.Sleep(delay) {
+			return attempts, true, err
+		}
+	}
+}
+
+func (policy *StartRetryPolicy) isRetryable(err error) bool {
+	var panicErr *PanicError
+	if IsPermanent(err) || errors.As(err, &panicErr) {
+		return false
+	}
+	return policy.Retryable == nil || policy.Retryable(err)
+}
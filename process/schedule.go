@@ -0,0 +1,227 @@
+package process
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule reports when a recurring task should next run.
+type Schedule interface {
+
+	// Returns the first run time after the given time, or the zero time if there is none.
+	Next(after time.Time) time.Time
+}
+
+// Every is a Schedule that runs at a fixed interval.
+type Every time.Duration
+
+func (d Every) Next(after time.Time) time.Time {
+	if d <= 0 {
+		return time.Time{}
+	}
+	return after.Add(time.Duration(d))
+}
+
+// OverlapPolicy specifies what a recurring task does when a run is due while the previous run is still in progress.
+type OverlapPolicy int32
+
+const (
+	Overlap_Skip       OverlapPolicy = iota // Runs that come due during a run are skipped (default)
+	Overlap_Coalesce                        // Runs that come due during a run are coalesced into a single run immediately after it
+	Overlap_Concurrent                      // Every run is started in its own child Context, regardless of other runs in progress
+)
+
+// GoScheduled starts a child Context of parent that calls fn each time the given Schedule comes due until it is closed.
+//
+// For Overlap_Skip and Overlap_Coalesce, fn is passed the returned Context and runs are never concurrent.
+// For Overlap_Concurrent, each run gets its own child of the returned Context.
+// The returned Context closes once the Schedule has no next run time (and any runs in progress finish).
+func GoScheduled(parent Context, label string, sched Schedule, policy OverlapPolicy, fn func(ctx Context)) (Context, error) {
+	return parent.StartChild(&Task{
+		Label:     label,
+		IdleClose: time.Nanosecond,
+		OnRun: func(ctx Context) {
+			clock := ClockOf(ctx)
+			var timer Timer
+			defer func() {
+				if timer != nil {
+					timer.Stop()
+				}
+			}()
+
+			for next := sched.Next(clock.Now()); !next.IsZero(); {
+				if timer == nil {
+					timer = clock.NewTimer(next.Sub(clock.Now()))
+				} else {
+					timer.Reset(next.Sub(clock.Now()))
+				}
+				select {
+				case <-timer.C():
+				case <-ctx.Closing():
+					return
+				}
+
+				if policy == Overlap_Concurrent {
+					ctx.Go(label, fn)
+					next = sched.Next(next)
+					continue
+				}
+
+				fn(ctx)
+				now := clock.Now()
+				if next = sched.Next(next); !next.After(now) {
+					if policy == Overlap_Coalesce {
+						next = now // a single immediate run for all runs that came due
+					} else {
+						next = sched.Next(now)
+					}
+				}
+			}
+		},
+	})
+}
+
+func (p *ctx) GoPeriodic(label string, interval time.Duration, fn func(ctx Context)) (Context, error) {
+	return GoScheduled(p, label, Every(interval), Overlap_Skip, fn)
+}
+
+// CronSchedule is a Schedule parsed from a standard 5-field cron expression (see ParseCron).
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64 // bit sets of matching values
+	domAny, dowAny                bool   // set if the respective field is "*"
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a cron expression of the form "minute hour day-of-month month day-of-week",
+// where each field is "*" or a comma separated list of values, ranges ("a-b"), and steps ("*/n" or "a-b/n").
+// Day-of-week is 0-6 (or 7) where 0 is Sunday.  If both day fields are restricted, a time matching either one matches.
+// The descriptors @yearly, @monthly, @weekly, @daily, and @hourly are also accepted.
+//
+// Times are matched in the location of the time passed to Next().
+func ParseCron(expr string) (*CronSchedule, error) {
+	if spec, ok := cronDescriptors[strings.TrimSpace(expr)]; ok {
+		expr = spec
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	sched := &CronSchedule{
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}
+	var err error
+	if sched.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("cron %q minute: %v", expr, err)
+	}
+	if sched.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("cron %q hour: %v", expr, err)
+	}
+	if sched.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("cron %q day of month: %v", expr, err)
+	}
+	if sched.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("cron %q month: %v", expr, err)
+	}
+	if sched.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("cron %q day of week: %v", expr, err)
+	}
+	if sched.dow&(1<<7) != 0 {
+		sched.dow |= 1 << 0 // 7 is also Sunday
+	}
+	return sched, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		lo, hi, step := min, max, 1
+
+		rng := part
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+
+		if rng != "*" {
+			var err error
+			if i := strings.IndexByte(rng, '-'); i >= 0 {
+				lo, err = strconv.Atoi(rng[:i])
+				if err == nil {
+					hi, err = strconv.Atoi(rng[i+1:])
+				}
+			} else {
+				lo, err = strconv.Atoi(rng)
+				hi = lo
+				if step > 1 {
+					hi = max
+				}
+			}
+			if err != nil || lo < min || hi > max || lo > hi {
+				return 0, fmt.Errorf("bad range %q (allowed %d-%d)", part, min, max)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (sched *CronSchedule) matchesDay(t time.Time) bool {
+	domMatch := sched.dom&(1<<uint(t.Day())) != 0
+	dowMatch := sched.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case sched.domAny && sched.dowAny:
+		return true
+	case sched.domAny:
+		return dowMatch
+	case sched.dowAny:
+		return domMatch
+	}
+	return domMatch || dowMatch
+}
+
+func (sched *CronSchedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	loc := t.Location()
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if sched.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !sched.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if sched.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if sched.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
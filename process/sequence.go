@@ -0,0 +1,32 @@
+package process
+
+import "fmt"
+
+// Sequence starts a child Context of parent with the given label and then starts each given Task as its child, in order.
+// Since StartChild() returns once a Task's OnStart completes, each Task starts only after its predecessors are up
+// (e.g. DB, then cache, then API).  When the returned Context closes, its children close in reverse order (see CloseOrder_LIFO),
+// each being Done before its predecessor is signaled to close.
+//
+// If a Task fails to start, the Tasks already started are closed (in reverse order) and the error is returned, prefixed with the failed Task's label.
+func Sequence(parent Context, label string, tasks ...*Task) (Context, error) {
+	seq, err := parent.StartChild(&Task{
+		Label:      label,
+		CloseOrder: CloseOrder_LIFO,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, task := range tasks {
+		if _, err := seq.StartChild(task); err != nil {
+			seq.Close()
+			<-seq.Done()
+			taskLabel := fmt.Sprintf("task %d", i)
+			if task != nil && task.Label != "" {
+				taskLabel = task.Label
+			}
+			return nil, fmt.Errorf("%s: %w", taskLabel, err)
+		}
+	}
+	return seq, nil
+}
@@ -0,0 +1,96 @@
+package process
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// DefaultCloseDeadline is the CloseDeadline used for root Contexts (those started via Start()) that don't specify one.
+var DefaultCloseDeadline time.Duration
+
+// closeDeadline returns the CloseDeadline in effect for this Context.
+func (p *ctx) closeDeadline(isRoot bool) time.Duration {
+	if p.task.CloseDeadline == 0 && isRoot {
+		return DefaultCloseDeadline
+	}
+	return p.task.CloseDeadline
+}
+
+// stillClosing returns the Contexts in the given subtree that are not yet Closed.
+func stillClosing(p Context) []Context {
+	var stuck []Context
+	Walk(p, func(ci Context, depth int) bool {
+		if ci.State() != Closed {
+			stuck = append(stuck, ci)
+		}
+		return true
+	})
+	return stuck
+}
+
+// onCloseDeadline is called when this Context has not reached Done() within its CloseDeadline after Close().
+func (p *ctx) onCloseDeadline(deadline time.Duration) {
+	stuck := stillClosing(p)
+
+	report := &strings.Builder{}
+	fmt.Fprintf(report, "not done %v after Close(); %d context(s) still closing:\n", deadline, len(stuck))
+	for _, ci := range stuck {
+		fmt.Fprintf(report, "    %03d %s (%s)\n", ci.ContextID(), ci.Label(), StateName(ci.State()))
+	}
+	report.WriteString("goroutine dump:\n")
+	report.Write(goroutineDump())
+	p.Warn(report.String())
+
+	if p.task.OnCloseDeadline != nil {
+		p.task.OnCloseDeadline(p, stuck)
+	}
+}
+
+// CloseTimeoutError is returned by CloseAndWait() when a Context does not reach Done() in time.
+type CloseTimeoutError struct {
+	Timeout time.Duration
+	Stuck   []string // ContextPath() of each Context that was still closing
+}
+
+func (err *CloseTimeoutError) Error() string {
+	return fmt.Sprintf("not done %v after Close(); %d context(s) still closing: %s", err.Timeout, len(err.Stuck), strings.Join(err.Stuck, ", "))
+}
+
+func (p *ctx) CloseAndWait(timeout time.Duration) error {
+	p.Close()
+	if timeout <= 0 {
+		<-p.Done()
+		return nil
+	}
+
+	timer := p.clock.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-p.Done():
+		return nil
+	case <-timer.C():
+	}
+
+	err := &CloseTimeoutError{Timeout: timeout}
+	for _, ci := range stillClosing(p) {
+		err.Stuck = append(err.Stuck, ci.ContextPath())
+	}
+	if len(err.Stuck) == 0 { // closed just as the timer fired
+		return nil
+	}
+	return err
+}
+
+// goroutineDump returns the stacks of all goroutines.
+func goroutineDump() []byte {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
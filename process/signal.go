@@ -0,0 +1,14 @@
+package process
+
+import (
+	"os"
+)
+
+// OnSignal closes root upon receiving any of the given signals (or the platform's default shutdown signals if none are given).
+// If another signal arrives before root is Done(), root's tree is written to stderr and the program exits immediately.
+// Signal handling stops once root is Done() or the returned func is called.
+//
+// This is equivalent to OnShutdown(root, SignalTrigger(sigs...)).
+func OnSignal(root Context, sigs ...os.Signal) (stop func()) {
+	return OnShutdown(root, SignalTrigger(sigs...))
+}
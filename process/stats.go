@@ -0,0 +1,44 @@
+package process
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats are a point-in-time account of a Context's lifecycle (see Context.Stats).
+type Stats struct {
+	StartTime       time.Time     `json:"start_time"`
+	Uptime          time.Duration `json:"uptime"`                   // Time since StartTime (or until Done, once closed)
+	StartDuration   time.Duration `json:"start_duration,omitempty"` // How long OnStart took
+	RunDuration     time.Duration `json:"run_duration,omitempty"`   // How long OnRun (or OnRunErr) has run so far, or took once returned
+	ChildrenStarted int64         `json:"children_started"`         // Cumulative number of children started
+	ChildCount      int           `json:"child_count"`              // Number of children currently open
+	CloseLatency    time.Duration `json:"close_latency,omitempty"`  // Time from Close() until Done (or 0 if not yet Done)
+}
+
+func (p *ctx) Stats() Stats {
+	now := p.clock.Now()
+	stats := Stats{
+		StartTime:     p.startTime,
+		StartDuration: time.Duration(atomic.LoadInt64(&p.startDuration)),
+		RunDuration:   time.Duration(atomic.LoadInt64(&p.runDuration)),
+	}
+
+	closedAt := atomic.LoadInt64(&p.closedAt)
+	if closedAt != 0 {
+		now = time.Unix(0, closedAt)
+		stats.CloseLatency = now.Sub(time.Unix(0, atomic.LoadInt64(&p.closingAt)))
+	}
+	stats.Uptime = now.Sub(p.startTime)
+	if stats.RunDuration == 0 {
+		if runStartedAt := atomic.LoadInt64(&p.runStartedAt); runStartedAt != 0 {
+			stats.RunDuration = now.Sub(time.Unix(0, runStartedAt))
+		}
+	}
+
+	p.subsMu.Lock()
+	stats.ChildrenStarted = p.numStarted
+	stats.ChildCount = int(p.numChildren)
+	p.subsMu.Unlock()
+	return stats
+}
@@ -60,7 +60,7 @@ func PrintContextTree(ctx Context, out io.Writer, verboseLevel int32) {
 	buf := new(strings.Builder)
 
 	buf.WriteString("\n")
-	printContextTree(ctx, buf, 0)
+	PrintTree(ctx, buf)
 
 	outStr := buf.String()
 	if out != nil {
@@ -0,0 +1,107 @@
+// Package systemd integrates a process.Context tree with systemd's service notification protocol (sd_notify),
+// allowing a daemon to run as a Type=notify unit with WatchdogSec set.
+package systemd
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/arcspace/go-cedar/process"
+)
+
+// Notify sends the given state (e.g. "READY=1") to the socket named by $NOTIFY_SOCKET.
+// If $NOTIFY_SOCKET is unset (i.e. not running under systemd), Notify does nothing and returns false.
+func Notify(state string) (sent bool, err error) {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return false, nil
+	}
+	if addr[0] == '@' {
+		addr = "\x00" + addr[1:] // abstract namespace
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns the watchdog timeout systemd expects pings within (per $WATCHDOG_USEC and $WATCHDOG_PID),
+// or 0 if the watchdog is not enabled for this process.
+func WatchdogInterval() (time.Duration, error) {
+	usecStr := os.Getenv("WATCHDOG_USEC")
+	if usecStr == "" {
+		return 0, nil
+	}
+	usec, err := strconv.ParseInt(usecStr, 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, errors.New("systemd: invalid WATCHDOG_USEC")
+	}
+	if pidStr := os.Getenv("WATCHDOG_PID"); pidStr != "" {
+		if pid, err := strconv.Atoi(pidStr); err != nil || pid != os.Getpid() {
+			return 0, nil
+		}
+	}
+	return time.Duration(usec) * time.Microsecond, nil
+}
+
+// Start reports READY=1 to systemd and starts a child Context of root that sends STOPPING=1 as soon as root starts closing
+// (before root's Task.OnClosing is called) and, if the watchdog is enabled, sends WATCHDOG=1 at half the watchdog interval until closed.
+//
+// Since StartChild() returns once a child's OnStart completes, Start should be called once root's children are started,
+// so that readiness is reported only once the tree is up.  If not running under systemd, Start does nothing and returns nil.
+func Start(root process.Context) (process.Context, error) {
+	if os.Getenv("NOTIFY_SOCKET") == "" {
+		return nil, nil
+	}
+	interval, err := WatchdogInterval()
+	if err != nil {
+		return nil, err
+	}
+
+	var stoppingOnce sync.Once
+	stopping := func() {
+		stoppingOnce.Do(func() {
+			Notify("STOPPING=1")
+		})
+	}
+	var cancelStopping func()
+
+	return root.StartChild(&process.Task{
+		Label: "systemd",
+		OnStart: func(ctx process.Context) error {
+			if _, err := Notify("READY=1"); err != nil {
+				return err
+			}
+			cancelStopping = root.OnClosing(stopping)
+			return nil
+		},
+		OnRun: func(ctx process.Context) {
+			if interval <= 0 {
+				<-ctx.Closing()
+				return
+			}
+			for range ctx.Tick(interval / 2) {
+				if _, err := Notify("WATCHDOG=1"); err != nil {
+					ctx.Warnf("watchdog ping failed: %v", err)
+				}
+			}
+		},
+		OnClosing: func() {
+			if cancelStopping != nil {
+				stopping()
+				cancelStopping()
+			}
+		},
+	})
+}
@@ -0,0 +1,56 @@
+package systemd_test
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arcspace/go-cedar/process"
+	"github.com/arcspace/go-cedar/process/systemd"
+)
+
+func TestStart(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	recv := func() string {
+		buf := make([]byte, 256)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		return string(buf[:n])
+	}
+
+	t.Setenv("NOTIFY_SOCKET", "")
+	noop, err := systemd.Start(process.NilContext)
+	require.NoError(t, err)
+	require.Nil(t, noop)
+
+	t.Setenv("NOTIFY_SOCKET", sock)
+	t.Setenv("WATCHDOG_USEC", "20000")
+	release := make(chan struct{})
+	root, _ := process.Start(&process.Task{
+		Label:     "root",
+		OnClosing: func() { <-release },
+	})
+	root.StartChild(&process.Task{Label: "svc"})
+	_, err = systemd.Start(root)
+	require.NoError(t, err)
+
+	require.Equal(t, "READY=1", recv())
+	require.Equal(t, "WATCHDOG=1", recv())
+
+	// STOPPING=1 is sent as soon as root starts closing, even while its OnClosing is running
+	root.Close()
+	deadline := time.Now().Add(time.Second)
+	for recv() != "STOPPING=1" {
+		require.True(t, time.Now().Before(deadline), "STOPPING=1 not sent while root's OnClosing was running")
+	}
+	close(release)
+	<-root.Done()
+}
@@ -0,0 +1,29 @@
+package process
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// taskRefHolder holds a TaskRef set via SetTaskRef(), allowing it to be swapped atomically.
+type taskRefHolder struct {
+	ref any
+}
+
+func (p *ctx) TaskRef() interface{} {
+	if holder := (*taskRefHolder)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&p.taskRef)))); holder != nil {
+		return holder.ref
+	}
+	return p.task.TaskRef
+}
+
+func (p *ctx) SetTaskRef(ref any) {
+	atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&p.taskRef)), unsafe.Pointer(&taskRefHolder{ref}))
+}
+
+// TaskRefAs returns the given Context's TaskRef as a T, returning false if it is not set or is not a T.
+// This is safe to call concurrently with SetTaskRef().
+func TaskRefAs[T any](ctx Context) (T, bool) {
+	ref, ok := ctx.TaskRef().(T)
+	return ref, ok
+}
@@ -0,0 +1,77 @@
+package process
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+func (p *ctx) Sleep(d time.Duration) bool {
+	timer := p.clock.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return true
+	case <-p.Closing():
+		return false
+	}
+}
+
+func (p *ctx) Tick(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	go func() {
+		defer close(ch)
+
+		timer := p.clock.NewTimer(d)
+		defer timer.Stop()
+		for {
+			select {
+			case t := <-timer.C():
+				timer.Reset(d)
+				select {
+				case ch <- t:
+				default: // like time.Ticker, drop ticks for a slow receiver
+				}
+			case <-p.Closing():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+const (
+	afterFunc_Pending int32 = iota
+	afterFunc_Fired
+	afterFunc_Stopped
+)
+
+func (p *ctx) AfterFunc(d time.Duration, fn func()) (stop func() bool) {
+	release := p.HoldIdle()
+	timer := p.clock.NewTimer(d)
+	chStop := make(chan struct{})
+	state := afterFunc_Pending
+
+	go func() {
+		defer release()
+		defer timer.Stop()
+
+		select {
+		case <-timer.C():
+			if atomic.CompareAndSwapInt32(&state, afterFunc_Pending, afterFunc_Fired) {
+				fn()
+			}
+		case <-p.Closing():
+			atomic.CompareAndSwapInt32(&state, afterFunc_Pending, afterFunc_Stopped)
+		case <-chStop:
+		}
+	}()
+
+	return func() bool {
+		if !atomic.CompareAndSwapInt32(&state, afterFunc_Pending, afterFunc_Stopped) {
+			return false
+		}
+		close(chStop)
+		return true
+	}
+}
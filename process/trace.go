@@ -0,0 +1,102 @@
+package process
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// traceIDKey is the std context key under which a trace ID is carried (see ContextWithTraceID).
+type traceIDKey struct{}
+
+// ContextWithTraceID returns a copy of the given std context.Context carrying the given trace ID, which is inherited by
+// Contexts started with it (see Task.Context and StartWithContext).  This allows a trace ID received by, say, an HTTP
+// handler to correlate the log output of the process subtree serving that request.
+func ContextWithTraceID(parent context.Context, traceID string) context.Context {
+	return context.WithValue(parent, traceIDKey{}, traceID)
+}
+
+// TraceIDFrom returns the trace ID carried by the given std context.Context (which can be a Context or derived from one),
+// returning "" if there is none.
+func TraceIDFrom(ctx context.Context) string {
+	if p, ok := ctx.(Context); ok {
+		return p.TraceID()
+	}
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
+
+func (p *ctx) SetTraceID(traceID string) {
+	atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&p.traceID)), unsafe.Pointer(&traceID))
+	p.Logger.SetLogLabel(p.logLabel())
+}
+
+func (p *ctx) TraceID() string {
+	if traceID := p.loadTraceID(); traceID != nil {
+		return *traceID
+	}
+	return ""
+}
+
+func (p *ctx) loadTraceID() *string {
+	return (*string)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(&p.traceID))))
+}
+
+func (p *ctx) WithTraceID(traceID string) Context {
+	return &traceScope{p, traceID}
+}
+
+// traceScope is a Context whose methods that start children give them a trace ID (see WithTraceID).
+type traceScope struct {
+	*ctx
+	traceID string
+}
+
+func (s *traceScope) StartChild(task *Task) (Context, error) {
+	if task != nil && task.TraceID == "" {
+		traced := *task
+		traced.TraceID = s.traceID
+		task = &traced
+	}
+	return s.ctx.StartChild(task)
+}
+
+func (s *traceScope) Go(label string, fn func(ctx Context)) (Context, error) {
+	return s.StartChild(&Task{
+		Label:     label,
+		IdleClose: time.Nanosecond,
+		OnRun:     fn,
+	})
+}
+
+func (s *traceScope) GoAfter(delay time.Duration, label string, fn func(ctx Context)) (Context, error) {
+	return s.GoAt(s.clock.Now().Add(delay), label, fn)
+}
+
+func (s *traceScope) GoAt(at time.Time, label string, fn func(ctx Context)) (Context, error) {
+	return s.StartChild(scheduledTask(at, label, fn))
+}
+
+func (s *traceScope) GoPeriodic(label string, interval time.Duration, fn func(ctx Context)) (Context, error) {
+	return GoScheduled(s, label, Every(interval), Overlap_Skip, fn)
+}
+
+func (s *traceScope) GoErr(label string, fn func(ctx Context) error) (Context, error) {
+	return s.goErr(s.StartChild, label, fn)
+}
+
+func (s *traceScope) WithTraceID(traceID string) Context {
+	return &traceScope{s.ctx, traceID}
+}
+
+// asCtx returns the *ctx underlying the given Context (including one returned by WithTraceID).
+func asCtx(ci Context) (*ctx, bool) {
+	switch p := ci.(type) {
+	case *ctx:
+		return p, true
+	case *traceScope:
+		return p.ctx, true
+	}
+	return nil, false
+}
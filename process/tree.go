@@ -0,0 +1,238 @@
+package process
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Walk calls fn for the given Context and then recursively for each of its descendants (depth-first).
+// If fn returns false, the children of the Context passed to fn are not visited.
+func Walk(root Context, fn func(ctx Context, depth int) bool) {
+	walk(root, 0, fn)
+}
+
+func walk(ctx Context, depth int, fn func(ctx Context, depth int) bool) {
+	if !fn(ctx, depth) {
+		return
+	}
+
+	var subBuf [20]Context
+	for _, ci := range ctx.GetChildren(subBuf[:0]) {
+		walk(ci, depth+1, fn)
+	}
+}
+
+// ChildOption filters or extends the Contexts returned by Context.GetChildren().
+type ChildOption func(filter *childFilter)
+
+type childFilter struct {
+	recursive   bool
+	labelPrefix string
+	states      []int32
+	tags        map[string]string
+}
+
+// WithRecursive causes GetChildren() to return all descendants (depth-first) rather than only direct children.
+// Other options filter which descendants are returned but not which are descended into.
+func WithRecursive() ChildOption {
+	return func(filter *childFilter) {
+		filter.recursive = true
+	}
+}
+
+// WithLabelPrefix causes GetChildren() to only return Contexts whose Label() starts with the given prefix.
+func WithLabelPrefix(prefix string) ChildOption {
+	return func(filter *childFilter) {
+		filter.labelPrefix = prefix
+	}
+}
+
+// WithState causes GetChildren() to only return Contexts whose State() is one of the given states.
+func WithState(states ...int32) ChildOption {
+	return func(filter *childFilter) {
+		filter.states = append(filter.states, states...)
+	}
+}
+
+// WithTag causes GetChildren() to only return Contexts whose Task.Tags has the given key set to the given value.
+// If given more than once, all the given tags must match.
+func WithTag(key, value string) ChildOption {
+	return func(filter *childFilter) {
+		if filter.tags == nil {
+			filter.tags = make(map[string]string)
+		}
+		filter.tags[key] = value
+	}
+}
+
+func (filter *childFilter) matches(ci *ctx) bool {
+	if filter.labelPrefix != "" && !strings.HasPrefix(ci.Label(), filter.labelPrefix) {
+		return false
+	}
+	for key, value := range filter.tags {
+		if tag, ok := ci.task.Tags[key]; !ok || tag != value {
+			return false
+		}
+	}
+	if len(filter.states) == 0 {
+		return true
+	}
+	state := ci.State()
+	for _, match := range filter.states {
+		if state == match {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDescendant returns true if ctx is root itself or one of root's descendants, as determined by walking ctx's parents
+// (so a Context that was moved via MoveTo is judged by where it is now).
+func IsDescendant(ctx, root Context) bool {
+	target, ok := asCtx(root)
+	if !ok {
+		return ctx == root
+	}
+	ci, ok := asCtx(ctx)
+	if !ok {
+		return false
+	}
+	for ; ci != nil; ci = ci.getParent() {
+		if ci == target {
+			return true
+		}
+	}
+	return false
+}
+
+// FindByPath returns the Context reached by following the given '/' separated Label() components from root, or nil if no such Context is open.
+// For example, FindByPath(root, "grpc/server/session-42") is equivalent to root.GetChild("grpc").GetChild("server").GetChild("session-42").
+// Leading, trailing, and repeated separators are ignored, so an empty path returns root.
+func FindByPath(root Context, path string) Context {
+	ctx := root
+	for _, label := range strings.Split(path, "/") {
+		if label == "" {
+			continue
+		}
+		if ctx = ctx.GetChild(label); ctx == nil {
+			return nil
+		}
+	}
+	return ctx
+}
+
+// ContextNode is a point-in-time snapshot of a Context and its descendants.
+type ContextNode struct {
+	ID             int64             `json:"id"`
+	Label          string            `json:"label"`
+	State          string            `json:"state"`
+	Tags           map[string]string `json:"tags,omitempty"`
+	ScheduledAt    *time.Time        `json:"scheduled_at,omitempty"`     // if State is "scheduled", when GoAt() / GoAfter() will run
+	RunsIn         time.Duration     `json:"runs_in,omitempty"`          // if State is "scheduled", the time remaining until ScheduledAt (per the Context's Clock)
+	IdleClose      time.Duration     `json:"idle_close,omitempty"`       // Task.IdleClose
+	IdleCloseArmed bool              `json:"idle_close_armed,omitempty"` // set if CloseWhenIdle() has been called
+	Children       []ContextNode     `json:"children,omitempty"`
+	History        []HistoryEntry    `json:"history,omitempty"` // see Task.HistorySize
+
+	Stats
+}
+
+// TreeSnapshot returns a snapshot of the given Context and all its descendants.
+func TreeSnapshot(root Context) ContextNode {
+	node := ContextNode{
+		ID:      root.ContextID(),
+		Label:   root.Label(),
+		State:   StateName(root.State()),
+		Tags:    root.Tags(),
+		Stats:   root.Stats(),
+		History: root.History(),
+	}
+
+	if p, ok := asCtx(root); ok {
+		node.IdleClose = p.task.IdleClose
+		node.IdleCloseArmed = atomic.LoadInt32(&p.idleClose) != 0
+		if node.State == "running" && atomic.LoadInt32(&p.scheduled) != 0 {
+			node.State = "scheduled"
+			runAt := p.task.runAt
+			node.ScheduledAt = &runAt
+			node.RunsIn = p.task.runAt.Sub(p.clock.Now())
+		} else if node.State == "running" && p.isDraining() {
+			node.State = "draining"
+		} else if node.State == "running" && p.IsPaused() {
+			node.State = "paused"
+		}
+	}
+
+	var subBuf [20]Context
+	for _, ci := range root.GetChildren(subBuf[:0]) {
+		node.Children = append(node.Children, TreeSnapshot(ci))
+	}
+	return node
+}
+
+// WriteText writes a human-readable, indented rendering of this snapshot.
+func (node *ContextNode) WriteText(out io.Writer) {
+	node.writeText(out, 0)
+}
+
+func (node *ContextNode) writeText(out io.Writer, depth int) {
+	indent := strings.Repeat("    ", depth)
+	if node.ScheduledAt != nil {
+		fmt.Fprintf(out, "%s%03d %s  (%s, runs in %v)\n", indent, node.ID, node.Label, node.State, node.RunsIn.Truncate(time.Millisecond))
+	} else {
+		fmt.Fprintf(out, "%s%03d %s  (%s, up %v)\n", indent, node.ID, node.Label, node.State, node.Uptime.Truncate(time.Millisecond))
+	}
+	if len(node.Tags) > 0 {
+		keys := make([]string, 0, len(node.Tags))
+		for key := range node.Tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		tags := make([]string, len(keys))
+		for i, key := range keys {
+			tags[i] = key + "=" + node.Tags[key]
+		}
+		fmt.Fprintf(out, "%s  # %s\n", indent, strings.Join(tags, " "))
+	}
+	for _, entry := range node.History {
+		fmt.Fprintf(out, "%s  | %v\n", indent, entry)
+	}
+	for i := range node.Children {
+		node.Children[i].writeText(out, depth+1)
+	}
+}
+
+// PrintTree writes a human-readable rendering of the given Context and all its descendants.
+func PrintTree(root Context, out io.Writer) {
+	node := TreeSnapshot(root)
+	node.WriteText(out)
+}
+
+// PrintTreeJSON writes a JSON rendering of the given Context and all its descendants.
+func PrintTreeJSON(root Context, out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(TreeSnapshot(root))
+}
+
+// StateName returns the name of the given Context state (see Context.State).
+func StateName(state int32) string {
+	switch state {
+	case Unstarted:
+		return "unstarted"
+	case Starting:
+		return "starting"
+	case Running:
+		return "running"
+	case Closing:
+		return "closing"
+	case Closed:
+		return "closed"
+	}
+	return "unknown"
+}
@@ -0,0 +1,161 @@
+package process
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// ShutdownTrigger is a source of shutdown requests, such as OS signals, allowing the same graceful shutdown path
+// (see OnShutdown) to be driven by whatever the platform provides or programmatically (e.g. in tests).
+type ShutdownTrigger interface {
+
+	// Delivers a description of each shutdown request (e.g. "interrupt") to ch until stop is called.
+	// Requests are dropped rather than blocking when ch is full.
+	Notify(ch chan<- string) (stop func())
+}
+
+// SignalTrigger returns a ShutdownTrigger for the given OS signals, or for the platform's default shutdown signals
+// if none are given (see DefaultShutdownTrigger).
+func SignalTrigger(sigs ...os.Signal) ShutdownTrigger {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	return signalTrigger(sigs)
+}
+
+// DefaultShutdownTrigger returns the ShutdownTrigger for the current platform, which is SignalTrigger(os.Interrupt, syscall.SIGTERM):
+//   - POSIX: SIGINT and SIGTERM
+//   - Windows: console control events, which the Go runtime delivers as signals: Ctrl-C and Ctrl-Break as os.Interrupt, and
+//     closing the console window, logging off, or system shutdown as syscall.SIGTERM (after which Windows allows only a few
+//     seconds to exit)
+func DefaultShutdownTrigger() ShutdownTrigger {
+	return SignalTrigger()
+}
+
+type signalTrigger []os.Signal
+
+func (sigs signalTrigger) Notify(ch chan<- string) (stop func()) {
+	chSig := make(chan os.Signal, 2)
+	chStop := make(chan struct{})
+	signal.Notify(chSig, sigs...)
+
+	go func() {
+		defer signal.Stop(chSig)
+		for {
+			select {
+			case sig := <-chSig:
+				select {
+				case ch <- sig.String():
+				default:
+				}
+			case <-chStop:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(chStop)
+		})
+	}
+}
+
+// ManualTrigger is a ShutdownTrigger fired by calling Trigger(), for programmatic shutdown (e.g. from an admin
+// endpoint or a test).
+type ManualTrigger struct {
+	mu       sync.Mutex
+	watchers map[*chan<- string]struct{}
+}
+
+// NewManualTrigger returns a new ManualTrigger.
+func NewManualTrigger() *ManualTrigger {
+	return &ManualTrigger{
+		watchers: make(map[*chan<- string]struct{}),
+	}
+}
+
+func (mt *ManualTrigger) Notify(ch chan<- string) (stop func()) {
+	key := &ch
+	mt.mu.Lock()
+	mt.watchers[key] = struct{}{}
+	mt.mu.Unlock()
+
+	return func() {
+		mt.mu.Lock()
+		delete(mt.watchers, key)
+		mt.mu.Unlock()
+	}
+}
+
+// Trigger delivers a shutdown request with the given reason to everything currently being notified.
+func (mt *ManualTrigger) Trigger(reason string) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	for ch := range mt.watchers {
+		select {
+		case *ch <- reason:
+		default:
+		}
+	}
+}
+
+// ForceExit is called by OnShutdown() when a second shutdown request arrives before root is Done().
+// Tests can replace this to observe a forced exit rather than exiting.
+var ForceExit = func() {
+	os.Exit(1)
+}
+
+// OnShutdown closes root upon the first request from any of the given triggers (or DefaultShutdownTrigger() if none are given).
+// If another request arrives before root is Done(), root's tree is written to stderr and ForceExit is called.
+// Triggers are no longer watched once root is Done() or the returned func is called.
+func OnShutdown(root Context, triggers ...ShutdownTrigger) (stop func()) {
+	if len(triggers) == 0 {
+		triggers = []ShutdownTrigger{DefaultShutdownTrigger()}
+	}
+
+	chReq := make(chan string, 2)
+	chStop := make(chan struct{})
+	stops := make([]func(), len(triggers))
+	for i, trigger := range triggers {
+		stops[i] = trigger.Notify(chReq)
+	}
+
+	go func() {
+		defer func() {
+			for _, stopTrigger := range stops {
+				stopTrigger()
+			}
+		}()
+
+		select {
+		case reason := <-chReq:
+			root.Warnf("received %v, closing (repeat to force exit)", reason)
+			root.Close()
+		case <-root.Done():
+			return
+		case <-chStop:
+			return
+		}
+
+		select {
+		case reason := <-chReq:
+			root.Errorf("received %v while closing, forcing exit", reason)
+			PrintTree(root, os.Stderr)
+			ForceExit()
+		case <-root.Done():
+		case <-chStop:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(chStop)
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package process
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// gRoots tracks all open root Contexts (those started via Start()) so that they can be inspected by a Watchdog.
+var gRoots = struct {
+	sync.Mutex
+	roots map[*ctx]struct{}
+}{
+	roots: make(map[*ctx]struct{}),
+}
+
+// gWatchdogs is the number of running watchdogs; while > 0, the stack that starts each Context is captured.
+var gWatchdogs int32
+
+func (p *ctx) addToRoots() {
+	gRoots.Lock()
+	gRoots.roots[p] = struct{}{}
+	gRoots.Unlock()
+}
+
+func (p *ctx) removeFromRoots() {
+	gRoots.Lock()
+	delete(gRoots.roots, p)
+	gRoots.Unlock()
+}
+
+// Roots returns all currently open root Contexts.
+func Roots() []Context {
+	gRoots.Lock()
+	defer gRoots.Unlock()
+
+	roots := make([]Context, 0, len(gRoots.roots))
+	for root := range gRoots.roots {
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+// captureStartStack records the caller's stack if a watchdog is running.
+// A restarted Context reuses the stack that started its first incarnation rather than that of the restart goroutine.
+func (p *ctx) captureStartStack() {
+	rs := p.restart
+	if rs != nil && rs.startPCs != nil {
+		p.startPCs = rs.startPCs
+		return
+	}
+	if atomic.LoadInt32(&gWatchdogs) > 0 {
+		var pcs [48]uintptr
+		n := runtime.Callers(2, pcs[:]) // skip runtime.Callers and captureStartStack (package frames are skipped by StartStack)
+		p.startPCs = append([]uintptr(nil), pcs[:n]...)
+		if rs != nil && rs.restarts == 0 {
+			rs.startPCs = p.startPCs
+		}
+	}
+}
+
+// pkgPrefix prefixes the names of all functions in this package (e.g. "github.com/arcspace/go-cedar/process.(*ctx).Go").
+var pkgPrefix = reflect.TypeOf(ctx{}).PkgPath() + "."
+
+// StartStack returns the stack of the goroutine that started the given Context, or "" if it was not captured
+// (stacks are only captured while a Watchdog is running).
+func StartStack(ci Context) string {
+	p, ok := asCtx(ci)
+	if !ok || len(p.startPCs) == 0 {
+		return ""
+	}
+
+	// Skip the frames within this package (e.g. StartChild, Go, or GoAfter) so the stack begins with their caller
+	b := &strings.Builder{}
+	inPkg := true
+	frames := runtime.CallersFrames(p.startPCs)
+	for {
+		frame, more := frames.Next()
+		if inPkg = inPkg && strings.HasPrefix(frame.Function, pkgPrefix); !inPkg {
+			fmt.Fprintf(b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// WatchdogOpts specifies what StartWatchdog() considers a leaked Context.
+type WatchdogOpts struct {
+	Interval        time.Duration // How often all Contexts are checked (10s if 0)
+	ClosingTimeout  time.Duration // Contexts that have been closing for longer than this are reported (30s if 0)
+	IdleRootTimeout time.Duration // If > 0, open roots that have had no children and no running OnRun for this long are reported
+
+	// Called with newly found leaks (each leak is reported once).  If nil, leaks are logged as warnings.
+	OnLeaks func(leaks []Leak)
+}
+
+// Leak describes a Context flagged by a watchdog.
+type Leak struct {
+	Context    Context
+	Reason     string        // "closing" or "idle root"
+	Age        time.Duration // How long the Context has been closing (or idle)
+	StartStack string        // Stack of the goroutine that started the Context (see StartStack)
+}
+
+func (leak Leak) String() string {
+	return fmt.Sprintf("%03d %s: %s for %v", leak.Context.ContextID(), leak.Context.Label(), leak.Reason, leak.Age.Truncate(time.Millisecond))
+}
+
+// StartWatchdog starts checking all Context trees for leaks as specified by opts, until the returned func is called.
+// While any watchdog is running, the stack that starts each Context is captured (so that it can be reported).
+func StartWatchdog(opts WatchdogOpts) (stop func()) {
+	if opts.Interval <= 0 {
+		opts.Interval = 10 * time.Second
+	}
+	if opts.ClosingTimeout <= 0 {
+		opts.ClosingTimeout = 30 * time.Second
+	}
+	if opts.OnLeaks == nil {
+		opts.OnLeaks = logLeaks
+	}
+
+	atomic.AddInt32(&gWatchdogs, 1)
+	chStop := make(chan struct{})
+	go func() {
+		defer atomic.AddInt32(&gWatchdogs, -1)
+
+		w := watchdog{
+			opts:      opts,
+			reported:  make(map[*ctx]struct{}),
+			idleSince: make(map[*ctx]time.Time),
+		}
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if leaks := w.check(time.Now()); len(leaks) > 0 {
+					opts.OnLeaks(leaks)
+				}
+			case <-chStop:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(chStop)
+		})
+	}
+}
+
+type watchdog struct {
+	opts      WatchdogOpts
+	reported  map[*ctx]struct{}  // Contexts already reported
+	idleSince map[*ctx]time.Time // when each root was first seen idle
+}
+
+func (w *watchdog) check(now time.Time) []Leak {
+	var leaks []Leak
+	report := func(p *ctx, reason string, age time.Duration) {
+		if _, seen := w.reported[p]; !seen {
+			w.reported[p] = struct{}{}
+			leaks = append(leaks, Leak{
+				Context:    p,
+				Reason:     reason,
+				Age:        age,
+				StartStack: StartStack(p),
+			})
+		}
+	}
+
+	live := make(map[*ctx]struct{})
+	for _, root := range Roots() {
+		rp := root.(*ctx)
+		if w.opts.IdleRootTimeout > 0 && rp.State() == Running && rp.ChildCount() == 0 && !rp.isRunning() {
+			since, ok := w.idleSince[rp]
+			if !ok {
+				since = now
+				w.idleSince[rp] = now
+			}
+			if idle := now.Sub(since); idle >= w.opts.IdleRootTimeout {
+				report(rp, "idle root", idle)
+			}
+		} else {
+			delete(w.idleSince, rp)
+		}
+
+		Walk(root, func(ci Context, depth int) bool {
+			p := ci.(*ctx)
+			live[p] = struct{}{}
+			if closingAt := atomic.LoadInt64(&p.closingAt); closingAt != 0 {
+				if age := p.clock.Now().Sub(time.Unix(0, closingAt)); age >= w.opts.ClosingTimeout {
+					report(p, "closing", age)
+				}
+			}
+			return true
+		})
+	}
+
+	// Forget Contexts that have since closed
+	for p := range w.reported {
+		if _, ok := live[p]; !ok {
+			delete(w.reported, p)
+		}
+	}
+	for p := range w.idleSince {
+		if _, ok := live[p]; !ok {
+			delete(w.idleSince, p)
+		}
+	}
+	return leaks
+}
+
+// isRunning returns true if this Context's OnRun has not yet returned.
+func (p *ctx) isRunning() bool {
+	if p.runDone == nil {
+		return false
+	}
+	select {
+	case <-p.runDone:
+		return false
+	default:
+		return true
+	}
+}
+
+func logLeaks(leaks []Leak) {
+	for _, leak := range leaks {
+		if leak.StartStack != "" {
+			leak.Context.Warnf("watchdog: %v, started by:\n%s", leak, leak.StartStack)
+		} else {
+			leak.Context.Warnf("watchdog: %v", leak)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"math/rand"
 	"time"
 )
 
@@ -44,6 +45,17 @@ func (eb *ExponentialBackoff) Reset() {
 	eb.current = eb.Min
 }
 
+// Jitter returns d randomly adjusted by up to +/- frac of its value (where 0 <= frac <= 1).
+func Jitter(d time.Duration, frac float64) time.Duration {
+	if frac <= 0 || d <= 0 {
+		return d
+	}
+	if frac > 1 {
+		frac = 1
+	}
+	return d + time.Duration((2*rand.Float64()-1)*frac*float64(d))
+}
+
 type Ticker interface {
 	Start()
 	Close()